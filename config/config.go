@@ -2,23 +2,46 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	AppPort      string
-	LogLevel     string
-	LogAddSource bool
-	DBHost       string
-	DBPort       int
-	DBUser       string
-	DBPassword   string
-	DBName       string
-	DBDebug      bool
-	BaseURLPath  string
-	DBSSLMode    string
+	AppPort                  string
+	LogLevel                 string
+	LogAddSource             bool
+	LogFormat                string
+	DBHost                   string
+	DBPort                   int
+	DBUser                   string
+	DBPassword               string
+	DBName                   string
+	StoreDriver              string
+	MigrateOnStart           bool
+	DBDebug                  bool
+	BaseURLPath              string
+	DBSSLMode                string
+	DBRequestTimeout         time.Duration
+	ShutdownTimeout          time.Duration
+	ReadinessTimeout         time.Duration
+	IdempotencyKeyTTL        time.Duration
+	IdempotencyPurgeInterval time.Duration
+	IdempotencyStaleAfter    time.Duration
+	PaymentCallbackSecret    string
+	PaymentCallbackTolerance time.Duration
+	JWTAlgorithms            []string
+	JWTSecret                string
+	JWTJWKSURL               string
+	JWTJWKSCacheTTL          time.Duration
+	JWTIssuer                string
+	JWTAudience              string
+	TraceServiceName         string
+	TraceExporter            string
+	TraceOTLPEndpoint        string
+	TraceOTLPInsecure        bool
+	TraceSampleRatio         float64
 }
 
 func LoadConfig() (*Config, error) {
@@ -28,26 +51,86 @@ func LoadConfig() (*Config, error) {
 	viper.AddConfigPath(".")
 	viper.AutomaticEnv()
 	viper.SetConfigType("yaml")
+	viper.SetDefault("STORE_DRIVER", "postgres")
+	viper.SetDefault("DB_REQUEST_TIMEOUT", "5s")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "15s")
+	viper.SetDefault("READINESS_TIMEOUT", "2s")
+	viper.SetDefault("IDEMPOTENCY_KEY_TTL", "24h")
+	viper.SetDefault("IDEMPOTENCY_PURGE_INTERVAL", "1h")
+	viper.SetDefault("IDEMPOTENCY_STALE_AFTER", "1m")
+	viper.SetDefault("PAYMENT_CALLBACK_TOLERANCE", "5m")
+	viper.SetDefault("JWT_ALGORITHMS", "HS256")
+	viper.SetDefault("JWT_JWKS_CACHE_TTL", "10m")
+	viper.SetDefault("TRACE_SERVICE_NAME", "cart-order-service")
+	viper.SetDefault("TRACE_EXPORTER", "none")
+	viper.SetDefault("TRACE_SAMPLE_RATIO", 1.0)
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("cannot read config file: %w", err)
 	}
 
 	config := &Config{
-		AppPort:     viper.GetString("APP_PORT"),
-		BaseURLPath: viper.GetString("BASE_URL_PATH"),
-		DBSSLMode:   viper.GetString("DB_SSL_MODE"),
-		DBUser:      viper.GetString("DB_USER"),
-		DBHost:      viper.GetString("DB_HOST"),
-		DBPassword:  viper.GetString("DB_PASSWORD"),
-		DBName:      viper.GetString("DB_NAME"),
-		DBDebug:     viper.GetBool("DB_DEBUG"),
-		DBPort:      viper.GetInt("DB_PORT"),
+		AppPort:      viper.GetString("APP_PORT"),
+		LogLevel:     viper.GetString("LOG_LEVEL"),
+		LogAddSource: viper.GetBool("LOG_ADD_SOURCE"),
+		LogFormat:    viper.GetString("LOG_FORMAT"),
+		BaseURLPath:  viper.GetString("BASE_URL_PATH"),
+		DBSSLMode:    viper.GetString("DB_SSL_MODE"),
+		DBUser:       viper.GetString("DB_USER"),
+		DBHost:       viper.GetString("DB_HOST"),
+		DBPassword:   viper.GetString("DB_PASSWORD"),
+		DBName:       viper.GetString("DB_NAME"),
+		DBDebug:      viper.GetBool("DB_DEBUG"),
+		DBPort:       viper.GetInt("DB_PORT"),
+		StoreDriver:  viper.GetString("STORE_DRIVER"),
+
+		MigrateOnStart: viper.GetBool("MIGRATE_ON_START"),
+
+		DBRequestTimeout: viper.GetDuration("DB_REQUEST_TIMEOUT"),
+		ShutdownTimeout:  viper.GetDuration("SHUTDOWN_TIMEOUT"),
+		ReadinessTimeout: viper.GetDuration("READINESS_TIMEOUT"),
+
+		IdempotencyKeyTTL:        viper.GetDuration("IDEMPOTENCY_KEY_TTL"),
+		IdempotencyPurgeInterval: viper.GetDuration("IDEMPOTENCY_PURGE_INTERVAL"),
+		IdempotencyStaleAfter:    viper.GetDuration("IDEMPOTENCY_STALE_AFTER"),
+
+		PaymentCallbackSecret:    viper.GetString("PAYMENT_CALLBACK_SECRET"),
+		PaymentCallbackTolerance: viper.GetDuration("PAYMENT_CALLBACK_TOLERANCE"),
+
+		JWTAlgorithms:   splitList(viper.GetStringSlice("JWT_ALGORITHMS")),
+		JWTSecret:       viper.GetString("JWT_SECRET"),
+		JWTJWKSURL:      viper.GetString("JWT_JWKS_URL"),
+		JWTJWKSCacheTTL: viper.GetDuration("JWT_JWKS_CACHE_TTL"),
+		JWTIssuer:       viper.GetString("JWT_ISSUER"),
+		JWTAudience:     viper.GetString("JWT_AUDIENCE"),
+
+		TraceServiceName:  viper.GetString("TRACE_SERVICE_NAME"),
+		TraceExporter:     viper.GetString("TRACE_EXPORTER"),
+		TraceOTLPEndpoint: viper.GetString("TRACE_OTLP_ENDPOINT"),
+		TraceOTLPInsecure: viper.GetBool("TRACE_OTLP_INSECURE"),
+		TraceSampleRatio:  viper.GetFloat64("TRACE_SAMPLE_RATIO"),
+	}
+
+	// A zero window would let retries take over reservations of requests that are still running.
+	if config.IdempotencyStaleAfter <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_STALE_AFTER must be positive, got %s", config.IdempotencyStaleAfter)
 	}
 
 	return config, nil
 }
 
+// splitList flattens a YAML list or a comma or space separated string into its items.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		items = append(items, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})...)
+	}
+
+	return items
+}
+
 func WriteTimeout() time.Duration {
 	return 10 * time.Second
 }
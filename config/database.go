@@ -20,12 +20,13 @@ func ConnectToDatabase(conn Connection) (*sql.DB, error) {
 		conn.Host, conn.Port, conn.User, conn.Password, conn.DBName)
 	db, err := sql.Open("postgres", psqlInfo)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("cannot open database: %w", err)
 	}
 
 	err = db.Ping()
 	if err != nil {
-		panic(err)
+		db.Close()
+		return nil, fmt.Errorf("cannot reach database: %w", err)
 	}
 
 	return db, nil
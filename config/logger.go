@@ -0,0 +1,38 @@
+package config
+
+import (
+	"cart-order-service/util/helper/requestid"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// NewLogger builds the application logger from the LogLevel, LogAddSource and LogFormat settings.
+// LogFormat is either "json" (the default) or "text".
+// Records logged with a request context carry the request ID.
+func NewLogger(cfg *Config) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.LogLevel != "" {
+		if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", cfg.LogLevel, err)
+		}
+	}
+
+	options := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: cfg.LogAddSource,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.LogFormat) {
+	case "", "json":
+		handler = slog.NewJSONHandler(os.Stdout, options)
+	case "text":
+		handler = slog.NewTextHandler(os.Stdout, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", cfg.LogFormat)
+	}
+
+	return slog.New(requestid.NewHandler(handler)), nil
+}
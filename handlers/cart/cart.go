@@ -3,6 +3,8 @@ package cart
 import (
 	"cart-order-service/helper"
 	model "cart-order-service/repository/models"
+	"cart-order-service/util/middleware"
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -11,10 +13,10 @@ import (
 
 // cartDto is an interface that defines the methods that our Handler struct depends on.
 type cartDto interface {
-	GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error)
-	AddCart(bReq model.Cart) (*uuid.UUID, error)
-	UpdateQty(bReq model.Cart) (string, error)
-	DeleteCart(bReq model.DeleteCartRequest) (string, error)
+	GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error)
+	AddCart(ctx context.Context, bReq model.Cart) (*model.AddCartResponse, error)
+	UpdateQty(ctx context.Context, bReq model.Cart) (string, error)
+	DeleteCart(ctx context.Context, bReq model.DeleteCartRequest) (string, error)
 }
 
 // Handler is a struct that holds a cartDto.
@@ -33,19 +35,19 @@ func NewHandler(cart cartDto) *Handler {
 func (h *Handler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 	userID := r.PathValue("user_id")
 	if userID == "" {
-		helper.HandleResponse(w, http.StatusBadRequest, "User ID is required")
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "User ID is required")
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid user ID")
 		return
 	}
 
 	var bReq model.GetCartRequest
 	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleDecodeError(w, err)
 		return
 	}
 
@@ -57,9 +59,9 @@ func (h *Handler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 		ProductID: pidSlice,
 	}
 
-	bResp, err := h.cart.GetCartByUserID(bReq)
+	bResp, err := h.cart.GetCartByUserID(r.Context(), bReq)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleDomainError(w, r, err)
 		return
 	}
 
@@ -69,18 +71,30 @@ func (h *Handler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AddCart(w http.ResponseWriter, r *http.Request) {
 	var bReq model.Cart
 	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleDecodeError(w, err)
 		return
 	}
 
-	if bReq.Qty == 0 {
-		helper.HandleResponse(w, http.StatusBadRequest, "Qty must be greater than 0")
+	userID := middleware.GetUserID(r.Context())
+	if bReq.UserID != uuid.Nil && bReq.UserID != userID {
+		helper.HandleError(w, http.StatusForbidden, helper.CodeForbidden, "You are not allowed to access resources of another user")
 		return
 	}
+	bReq.UserID = userID
 
-	bResp, err := h.cart.AddCart(bReq)
+	if bReq.Qty <= 0 {
+		helper.HandleError(w, http.StatusUnprocessableEntity, helper.CodeValidation, "Qty must be greater than 0", helper.ErrorDetail{Field: "qty", Rule: "gt", Message: "must be greater than 0"})
+		return
+	}
+
+	bResp, err := h.cart.AddCart(r.Context(), bReq)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	if bResp.Created {
+		helper.HandleResponse(w, http.StatusCreated, bResp)
 		return
 	}
 
@@ -92,20 +106,20 @@ func (h *Handler) UpdateCart(w http.ResponseWriter, r *http.Request) {
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid user ID")
 		return
 	}
 
 	var bReq model.Cart
 	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleDecodeError(w, err)
 		return
 	}
 	bReq.UserID = uid
 
-	bResp, err := h.cart.UpdateQty(bReq)
+	bResp, err := h.cart.UpdateQty(r.Context(), bReq)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleDomainError(w, r, err)
 		return
 	}
 
@@ -117,20 +131,20 @@ func (h *Handler) DeleteCart(w http.ResponseWriter, r *http.Request) {
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid user ID")
 		return
 	}
 
 	var bReq model.DeleteCartRequest
 	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleDecodeError(w, err)
 		return
 	}
 	bReq.UserID = uid
 
-	bResp, err := h.cart.DeleteCart(bReq)
+	bResp, err := h.cart.DeleteCart(r.Context(), bReq)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleDomainError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"cart-order-service/helper"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	statusOK          = "ok"
+	statusFailed      = "failed"
+	statusUnavailable = "unavailable"
+)
+
+// Check is a named readiness check; it must return nil when the dependency is usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Handler is a struct that serves the liveness and readiness probes.
+type Handler struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+// Every readiness check must finish within timeout.
+func NewHandler(timeout time.Duration, checks ...Check) *Handler {
+	return &Handler{timeout, checks}
+}
+
+// Liveness is a handler function that reports that the process is up.
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	helper.HandleResponse(w, http.StatusOK, healthResponse{Status: statusOK})
+}
+
+// Readiness is a handler function that runs every readiness check and responds with the status of each check.
+// When a check fails it responds with 503 and a generic error detail per failing check;
+// the reasons are logged rather than exposed on this unauthenticated endpoint.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	bResp := healthResponse{
+		Status: statusOK,
+		Checks: make(map[string]string, len(h.checks)),
+	}
+
+	var failures []helper.ErrorDetail
+	for _, check := range h.checks {
+		if err := check.Run(ctx); err != nil {
+			slog.WarnContext(ctx, "readiness check failed", slog.String("check", check.Name), slog.Any("error", err))
+			failures = append(failures, helper.ErrorDetail{Field: check.Name, Message: "check failed"})
+			bResp.Checks[check.Name] = statusFailed
+			continue
+		}
+		bResp.Checks[check.Name] = statusOK
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if len(failures) > 0 {
+		bResp.Status = statusUnavailable
+		helper.HandleErrorData(w, http.StatusServiceUnavailable, bResp, helper.CodeNotReady, "Service is not ready", failures...)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
@@ -3,16 +3,29 @@ package order
 import (
 	"cart-order-service/helper"
 	model "cart-order-service/repository/models"
+	"cart-order-service/util/middleware"
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/google/uuid"
 )
 
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 type orderDto interface {
-	CreateOrder(bReq model.Order) (*uuid.UUID, error)
-	UpdatePayment(bReq model.UpdateRequest) (*string, error)
+	CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, error)
+	UpdateStatus(ctx context.Context, bReq model.UpdateRequest) (*string, error)
+	Checkout(ctx context.Context, bReq model.CheckoutRequest) (*model.CheckoutResponse, error)
+	GetOrder(ctx context.Context, orderID, userID uuid.UUID) (*model.OrderDetail, error)
+	ListOrders(ctx context.Context, bReq model.ListOrdersRequest) (*model.ListOrdersResponse, error)
+	GetOrderTimeline(ctx context.Context, orderID, userID uuid.UUID) ([]model.OrderItemsLogs, error)
 }
 
 type Handler struct {
@@ -27,24 +40,33 @@ func NewHandler(order orderDto, validator *validator.Validate) *Handler {
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var bReq model.Order
 	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleDecodeError(w, err)
 		return
 	}
 
+	userID := middleware.GetUserID(r.Context())
+	if bReq.UserID != uuid.Nil && bReq.UserID != userID {
+		helper.HandleError(w, http.StatusForbidden, helper.CodeForbidden, "You are not allowed to access resources of another user")
+		return
+	}
+	bReq.UserID = userID
+
 	bReq.RefCode = helper.GenerateRefCode()
+	bReq.Status = model.OrderStatusPending
+	bReq.IsPaid = false
 
 	if bReq.ProductOrder == nil {
 		bReq.ProductOrder = json.RawMessage("[]")
 	}
 
 	if err := h.validator.Struct(bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleValidationError(w, err)
 		return
 	}
 
-	bRes, err := h.order.CreateOrder(bReq)
+	bRes, err := h.order.CreateOrder(r.Context(), bReq)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleDomainError(w, r, err)
 		return
 	}
 
@@ -54,21 +76,230 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 	var bReq model.UpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleDecodeError(w, err)
 		return
 	}
 
 	if err := h.validator.Struct(&bReq); err != nil {
-		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		helper.HandleValidationError(w, err)
+		return
+	}
+
+	if !model.IsValidOrderStatus(bReq.Status) {
+		helper.HandleError(w, http.StatusUnprocessableEntity, helper.CodeValidation, "Unknown order status", helper.ErrorDetail{Field: "status", Message: "is not a known order status"})
+		return
+	}
+
+	if bReq.Status == model.OrderStatusPaid && bReq.TransactionID == "" {
+		helper.HandleError(w, http.StatusUnprocessableEntity, helper.CodeValidation, "Transaction ID is required for paid orders", helper.ErrorDetail{Field: "transaction_id", Rule: "required", Message: "is required for paid orders"})
 		return
 	}
 
+	bReq.Actor = model.OrderActorPaymentGateway
+
 	// payment success
-	message, err := h.order.UpdatePayment(bReq)
+	message, err := h.order.UpdateStatus(r.Context(), bReq)
 	if err != nil {
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleDomainError(w, r, err)
 		return
 	}
 
 	helper.HandleResponse(w, http.StatusOK, message)
 }
+
+// Checkout is a handler function that turns the cart of the user in the URL path into an order.
+// It responds with the new order ID and ref code.
+func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
+	uid, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid user ID")
+		return
+	}
+
+	var bReq model.CheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
+		helper.HandleDecodeError(w, err)
+		return
+	}
+
+	bReq.UserID = uid
+	bReq.OrderNumber = helper.GenerateOrderNumber()
+	bReq.RefCode = helper.GenerateRefCode()
+
+	if err := h.validator.Struct(bReq); err != nil {
+		helper.HandleValidationError(w, err)
+		return
+	}
+
+	bResp, err := h.order.Checkout(r.Context(), bReq)
+	if err != nil {
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusCreated, bResp)
+}
+
+// GetOrder is a handler function that returns the order in the URL path together with its status history.
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(r.PathValue("order_id"))
+	if err != nil {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid order ID")
+		return
+	}
+
+	bResp, err := h.order.GetOrder(r.Context(), orderID, ownerFilter(r))
+	if err != nil {
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+// GetOrderTimeline is a handler function that returns the status timeline of the order in the URL path,
+// oldest entry first.
+func (h *Handler) GetOrderTimeline(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(r.PathValue("order_id"))
+	if err != nil {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid order ID")
+		return
+	}
+
+	bResp, err := h.order.GetOrderTimeline(r.Context(), orderID, ownerFilter(r))
+	if err != nil {
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+// ListOrders is a handler function that returns a page of the orders of the authenticated user,
+// or of every user for admins.
+// It supports the user_id, status, from, to, sort, limit and cursor query parameters;
+// from and to accept either RFC 3339 timestamps or YYYY-MM-DD dates, and to is exclusive.
+func (h *Handler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	bReq := model.ListOrdersRequest{
+		UserID: ownerFilter(r),
+		Status: query.Get("status"),
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+		Limit:  defaultListLimit,
+	}
+
+	if userID := query.Get("user_id"); userID != "" {
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid user ID")
+			return
+		}
+
+		if bReq.UserID != uuid.Nil && uid != bReq.UserID {
+			helper.HandleError(w, http.StatusForbidden, helper.CodeForbidden, "You are not allowed to access resources of another user")
+			return
+		}
+		bReq.UserID = uid
+	}
+
+	if bReq.Status != "" && !model.IsValidOrderStatus(bReq.Status) {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Unknown order status")
+		return
+	}
+
+	if bReq.Sort == "" {
+		bReq.Sort = "desc"
+	}
+	if bReq.Sort != "asc" && bReq.Sort != "desc" {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Sort must be asc or desc")
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxListLimit {
+			helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Limit must be between 1 and "+strconv.Itoa(maxListLimit))
+			return
+		}
+		bReq.Limit = n
+	}
+
+	for param, dest := range map[string]**time.Time{"from": &bReq.From, "to": &bReq.To} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := parseTime(value)
+		if err != nil {
+			helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid "+param+" time")
+			return
+		}
+		*dest = &t
+	}
+
+	bResp, err := h.order.ListOrders(r.Context(), bReq)
+	if err != nil {
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+// parseTime parses a query parameter given either as an RFC 3339 timestamp or as a YYYY-MM-DD date.
+// The offset of a timestamp is kept so that the store can compare it as an instant.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
+
+// UpdateOrderStatus is an admin handler function that moves the order in the URL path to another status.
+// The move must still be allowed by the order status machine.
+func (h *Handler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(r.PathValue("order_id"))
+	if err != nil {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid order ID")
+		return
+	}
+
+	var bReq model.UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
+		helper.HandleDecodeError(w, err)
+		return
+	}
+
+	bReq.OrderID = orderID
+	bReq.Actor = middleware.GetUserID(r.Context()).String()
+
+	if err := h.validator.Struct(&bReq); err != nil {
+		helper.HandleValidationError(w, err)
+		return
+	}
+
+	if !model.IsValidOrderStatus(bReq.Status) {
+		helper.HandleError(w, http.StatusUnprocessableEntity, helper.CodeValidation, "Unknown order status", helper.ErrorDetail{Field: "status", Message: "is not a known order status"})
+		return
+	}
+
+	message, err := h.order.UpdateStatus(r.Context(), bReq)
+	if err != nil {
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, message)
+}
+
+// ownerFilter returns the user whose orders the caller may access, or uuid.Nil for admins who may access every order.
+func ownerFilter(r *http.Request) uuid.UUID {
+	if middleware.HasScope(r.Context(), middleware.ScopeAdmin) {
+		return uuid.Nil
+	}
+
+	return middleware.GetUserID(r.Context())
+}
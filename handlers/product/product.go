@@ -0,0 +1,53 @@
+package product
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type productDto interface {
+	SetPrice(ctx context.Context, bReq model.ProductPrice) error
+}
+
+type Handler struct {
+	product   productDto
+	validator *validator.Validate
+}
+
+func NewHandler(product productDto, validator *validator.Validate) *Handler {
+	return &Handler{product, validator}
+}
+
+// SetPrice is an admin handler function that sets the unit price charged at checkout for the product in the URL path.
+func (h *Handler) SetPrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(r.PathValue("product_id"))
+	if err != nil {
+		helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid product ID")
+		return
+	}
+
+	var bReq model.ProductPrice
+	if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
+		helper.HandleDecodeError(w, err)
+		return
+	}
+	bReq.ProductID = productID
+
+	if err := h.validator.Struct(bReq); err != nil {
+		helper.HandleValidationError(w, err)
+		return
+	}
+
+	if err := h.product.SetPrice(r.Context(), bReq); err != nil {
+		helper.HandleDomainError(w, r, err)
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, "Product price updated")
+}
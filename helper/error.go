@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"cart-order-service/util/apperror"
+	"net/http"
+)
+
+// HandleDomainError writes the response matching the kind of the domain error found in err:
+// not found is 404, conflict and invalid transition are 409, forbidden is 403 and validation is 422.
+// Any other error is logged and reported as a generic 500.
+func HandleDomainError(w http.ResponseWriter, r *http.Request, err error) {
+	domainErr, ok := apperror.As(err)
+	if !ok {
+		HandleInternalError(w, r, err)
+		return
+	}
+
+	// The full chain is used as message so that context added with fmt.Errorf("%w ...") is kept.
+	message := err.Error()
+
+	switch domainErr.Kind {
+	case apperror.KindNotFound:
+		HandleError(w, http.StatusNotFound, CodeNotFound, message)
+	case apperror.KindConflict:
+		HandleError(w, http.StatusConflict, CodeConflict, message)
+	case apperror.KindInvalidTransition:
+		HandleError(w, http.StatusConflict, CodeInvalidTransition, message)
+	case apperror.KindForbidden:
+		HandleError(w, http.StatusForbidden, CodeForbidden, message)
+	case apperror.KindValidation:
+		var details []ErrorDetail
+		if domainErr.Field != "" {
+			details = append(details, ErrorDetail{Field: domainErr.Field, Message: message})
+		}
+		HandleError(w, http.StatusUnprocessableEntity, CodeValidation, message, details...)
+	default:
+		HandleInternalError(w, r, err)
+	}
+}
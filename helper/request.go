@@ -12,3 +12,11 @@ func GenerateRefCode() string {
 
 	return refCode
 }
+
+func GenerateOrderNumber() string {
+	currentUnixTime := time.Now().UnixNano()
+
+	orderNumber := fmt.Sprintf("ORD%d", currentUnixTime)
+
+	return orderNumber
+}
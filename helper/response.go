@@ -1,7 +1,9 @@
 package helper
 
 import (
+	"cart-order-service/util/helper/requestid"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 )
 
@@ -9,8 +11,88 @@ const (
 	SUCCESS_MESSSAGE string = "Success"
 )
 
+// Machine-readable error codes returned in the error envelope.
+const (
+	CodeBadRequest            = "bad_request"
+	CodeInvalidBody           = "invalid_body"
+	CodeInvalidParameter      = "invalid_parameter"
+	CodeValidation            = "validation_failed"
+	CodeUnauthorized          = "unauthorized"
+	CodeInvalidSignature      = "invalid_signature"
+	CodeForbidden             = "forbidden"
+	CodeNotFound              = "not_found"
+	CodeConflict              = "conflict"
+	CodeInvalidTransition     = "invalid_status_transition"
+	CodeIdempotencyMismatch   = "idempotency_key_mismatch"
+	CodeIdempotencyInProgress = "idempotency_key_in_progress"
+	CodePayloadTooLarge       = "payload_too_large"
+	CodeNotReady              = "not_ready"
+	CodeInternal              = "internal_error"
+)
+
+// Response is the envelope of every JSON response.
+// Data is null on errors and Error is omitted on success.
+type Response struct {
+	Data      interface{} `json:"data"`
+	Error     *Error      `json:"error,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Error describes why a request failed.
+type Error struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail describes one invalid field of a request.
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// HandleResponse writes data as the data of a JSON response.
 func HandleResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	writeResponse(w, statusCode, Response{Data: data})
+}
+
+// HandleError writes an error response with the given code, human readable message and optional field details.
+func HandleError(w http.ResponseWriter, statusCode int, code, message string, details ...ErrorDetail) {
+	writeResponse(w, statusCode, Response{
+		Error: &Error{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
+// HandleErrorData writes an error response that still carries data, for errors that come with a result,
+// such as a report of which parts of an operation failed.
+func HandleErrorData(w http.ResponseWriter, statusCode int, data interface{}, code, message string, details ...ErrorDetail) {
+	writeResponse(w, statusCode, Response{
+		Data: data,
+		Error: &Error{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
+// HandleInternalError logs err and writes a generic 500 response, so that driver and
+// other internal messages never reach the client.
+func HandleInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "request failed", slog.String("path", r.URL.Path), slog.Any("error", err))
+	HandleError(w, http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+// writeResponse sets the request ID found in the X-Request-ID response header and writes the envelope.
+func writeResponse(w http.ResponseWriter, statusCode int, bResp Response) {
+	bResp.RequestID = w.Header().Get(requestid.Header)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+	json.NewEncoder(w).Encode(bResp)
 }
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+// NewValidator returns a validator that reports fields by their JSON name.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	return v
+}
+
+// HandleValidationError writes a 422 response listing every field rejected by the validator.
+// Errors that are not validation errors are reported as invalid request bodies.
+func HandleValidationError(w http.ResponseWriter, err error) {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		HandleError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	details := make([]ErrorDetail, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		details = append(details, ErrorDetail{
+			Field:   fieldError.Field(),
+			Rule:    fieldError.Tag(),
+			Message: validationMessage(fieldError),
+		})
+	}
+
+	HandleError(w, http.StatusUnprocessableEntity, CodeValidation, "Request validation failed", details...)
+}
+
+// HandleDecodeError writes a 400 response for a request body that is not valid JSON for the expected model.
+func HandleDecodeError(w http.ResponseWriter, err error) {
+	HandleError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+}
+
+// validationMessage describes the rule a field failed in plain words.
+func validationMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fieldError.Param()
+	case "gte", "min":
+		return "must be at least " + fieldError.Param()
+	case "lt":
+		return "must be less than " + fieldError.Param()
+	case "lte", "max":
+		return "must be at most " + fieldError.Param()
+	case "oneof":
+		return "must be one of " + fieldError.Param()
+	default:
+		return fmt.Sprintf("failed the %s rule", fieldError.Tag())
+	}
+}
@@ -3,53 +3,158 @@ package main
 import (
 	"cart-order-service/config"
 	cartHandler "cart-order-service/handlers/cart"
-	"cart-order-service/repository/cart"
-	"cart-order-service/repository/order"
+	"cart-order-service/handlers/health"
+	"cart-order-service/helper"
 	"cart-order-service/routes"
 	cartUsecase "cart-order-service/usecase/cart"
-	"database/sql"
+	"cart-order-service/util/helper/jwt"
+	"cart-order-service/util/metrics"
+	"cart-order-service/util/middleware"
+	"cart-order-service/util/tracing"
+	"cart-order-service/worker"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	orderHandler "cart-order-service/handlers/order"
+	productHandler "cart-order-service/handlers/product"
 	orderUseCase "cart-order-service/usecase/order"
+	productUsecase "cart-order-service/usecase/product"
 
 	"github.com/go-playground/validator"
 )
 
 func main() {
+	if err := run(); err != nil {
+		slog.Error("service failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+// run starts the service and blocks until it is asked to stop with SIGINT or SIGTERM.
+// On shutdown the HTTP server is drained first, then the background workers are stopped
+// and finally the database pool is closed.
+func run() error {
+	migrate := flag.Bool("migrate", false, "apply pending database migrations before starting, same as MIGRATE_ON_START")
+	flag.Parse()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		return
+		return err
+	}
+	cfg.MigrateOnStart = cfg.MigrateOnStart || *migrate
+
+	logger, err := config.NewLogger(cfg)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName:  cfg.TraceServiceName,
+		Exporter:     cfg.TraceExporter,
+		OTLPEndpoint: cfg.TraceOTLPEndpoint,
+		OTLPInsecure: cfg.TraceOTLPInsecure,
+		SampleRatio:  cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot set up tracing: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+
+	store, err := openStorage(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			logger.Error("failed to close database", slog.Any("error", err))
+		}
+	}()
+	if store.db == nil {
+		logger.Warn("using in-memory stores, data is lost on restart", slog.String("store_driver", cfg.StoreDriver))
 	}
 
-	sqlDb, err := config.ConnectToDatabase(config.Connection{
-		Host:     cfg.DBHost,
-		Port:     cfg.DBPort,
-		User:     cfg.DBUser,
-		Password: cfg.DBPassword,
-		DBName:   cfg.DBName,
+	verifier, err := jwt.NewVerifier(jwt.Config{
+		Algorithms:   cfg.JWTAlgorithms,
+		Secret:       cfg.JWTSecret,
+		JWKSURL:      cfg.JWTJWKSURL,
+		JWKSCacheTTL: cfg.JWTJWKSCacheTTL,
+		Issuer:       cfg.JWTIssuer,
+		Audience:     cfg.JWTAudience,
 	})
 	if err != nil {
-		return
+		return fmt.Errorf("cannot set up token verification: %w", err)
 	}
-	defer sqlDb.Close()
 
-	validator := validator.New()
+	// The workers are detached from the signal so that they keep running while the server drains;
+	// the deferred Stop cancels them once routes.Run has returned.
+	workers := setupWorkers(cfg, store.idempotency, logger)
+	workers.Start(context.WithoutCancel(ctx))
+	defer workers.Stop()
+
+	validator := helper.NewValidator()
+
+	metrics := metrics.New(store.db)
+
+	routes := setupRoutes(cfg, store, validator, verifier, metrics, logger)
+	routes.Health = health.NewHandler(cfg.ReadinessTimeout,
+		append(store.checks, health.Check{Name: "workers", Run: workers.Check})...,
+	)
 
-	routes := setupRoutes(sqlDb, validator)
-	routes.Run(cfg.AppPort)
+	return routes.Run(ctx, cfg.AppPort, cfg.ShutdownTimeout)
 }
 
-func setupRoutes(db *sql.DB, validator *validator.Validate) *routes.Routes {
-	cartRepository := cart.NewStore(db)
-	cartUseCase := cartUsecase.NewCart(cartRepository)
+func setupRoutes(cfg *config.Config, store *storage, validator *validator.Validate, verifier *jwt.Verifier, metrics *metrics.Metrics, logger *slog.Logger) *routes.Routes {
+	cartUseCase := cartUsecase.NewCart(store.cart)
 	cartHandler := cartHandler.NewHandler(cartUseCase)
 
-	orderRepository := order.NewStore(db)
-	orderUseCase := orderUseCase.NewOrder(orderRepository)
+	orderUseCase := orderUseCase.NewOrder(store.order, store.cart, store.product, store.transactor, metrics)
 	orderHandler := orderHandler.NewHandler(orderUseCase, validator)
 
+	productUseCase := productUsecase.NewProduct(store.product)
+	productHandler := productHandler.NewHandler(productUseCase, validator)
+
 	return &routes.Routes{
-		Cart:  cartHandler,
-		Order: orderHandler,
+		Logger:       logger,
+		Cart:         cartHandler,
+		Order:        orderHandler,
+		Product:      productHandler,
+		Metrics:      metrics,
+		Authenticate: middleware.Authentication(verifier),
+		Idempotency:  middleware.Idempotency(store.idempotency, cfg.IdempotencyStaleAfter, logger),
+
+		VerifyCallback: middleware.VerifySignature(cfg.PaymentCallbackSecret, cfg.PaymentCallbackTolerance),
+		DBTimeout:      cfg.DBRequestTimeout,
 	}
 }
+
+func setupWorkers(cfg *config.Config, idempotencyRepository idempotencyStore, logger *slog.Logger) *worker.Group {
+	return worker.NewGroup(logger, worker.Worker{
+		Name:     "idempotency-key-purge",
+		Interval: cfg.IdempotencyPurgeInterval,
+		Run: func(ctx context.Context) error {
+			deleted, err := idempotencyRepository.DeleteExpired(ctx, cfg.IdempotencyKeyTTL)
+			if err != nil {
+				return err
+			}
+
+			logger.DebugContext(ctx, "purged expired idempotency keys", slog.Int64("deleted", deleted))
+			return nil
+		},
+	})
+}
@@ -0,0 +1,63 @@
+// Package migrations embeds the goose SQL migrations in sql/ so that the service can apply them itself on startup.
+// migration.go next to it is the goose CLI for running them by hand.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// lockID is the key of the Postgres advisory lock held while migrating,
+// so that instances starting together apply every migration once.
+const lockID int64 = 7_146_284_020_601
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// files is the content of sql/, the migrations the binary ships with.
+var files, _ = fs.Sub(embedded, "sql")
+
+// Up applies every pending migration to db under the migration lock and returns the schema version it left db at.
+// While another instance holds the lock it waits for it, giving up after five minutes.
+func Up(ctx context.Context, db *sql.DB) (int64, error) {
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(lockID))
+	if err != nil {
+		return 0, err
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, files, goose.WithSessionLocker(locker))
+	if err != nil {
+		return 0, fmt.Errorf("cannot load migrations: %w", err)
+	}
+
+	if _, err := provider.Up(ctx); err != nil {
+		return 0, fmt.Errorf("cannot apply migrations: %w", err)
+	}
+
+	return provider.GetDBVersion(ctx)
+}
+
+// Latest returns the version of the newest embedded migration, the schema version the binary expects.
+func Latest() int64 {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return 0
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		version, err := goose.NumericComponent(entry.Name())
+		if err != nil {
+			continue
+		}
+		latest = max(latest, version)
+	}
+
+	return latest
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE order_status_logs ADD COLUMN actor VARCHAR(100) NOT NULL DEFAULT 'system';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_status_logs DROP COLUMN IF EXISTS actor;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Merge duplicated active lines into the oldest line of each user/product pair before enforcing uniqueness.
+WITH ranked AS (
+    SELECT
+        id,
+        ROW_NUMBER() OVER (PARTITION BY user_id, product_id ORDER BY created_at, id) AS rn,
+        SUM(qty) OVER (PARTITION BY user_id, product_id) AS total_qty
+    FROM cart_items
+    WHERE deleted_at IS NULL
+)
+UPDATE cart_items c
+SET
+    qty = CASE WHEN r.rn = 1 THEN r.total_qty ELSE c.qty END,
+    updated_at = CASE WHEN r.rn = 1 THEN now() ELSE c.updated_at END,
+    deleted_at = CASE WHEN r.rn = 1 THEN NULL ELSE now() END
+FROM ranked r
+WHERE c.id = r.id AND (r.rn > 1 OR r.total_qty <> c.qty);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_cart_items_user_product_active ON cart_items (user_id, product_id) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS uq_cart_items_user_product_active;
+-- +goose StatementEnd
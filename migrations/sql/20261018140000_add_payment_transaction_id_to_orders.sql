@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders ADD COLUMN payment_transaction_id VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_orders_payment_transaction_id ON orders (payment_transaction_id) WHERE payment_transaction_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS uq_orders_payment_transaction_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_transaction_id;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE product_prices (
+    product_id UUID PRIMARY KEY,
+    price DOUBLE PRECISION NOT NULL CHECK (price > 0),
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_prices CASCADE;
+-- +goose StatementEnd
@@ -1,11 +1,12 @@
 package cart
 
 import (
+	"cart-order-service/repository"
+	"cart-order-service/repository/internal/query"
 	model "cart-order-service/repository/models"
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/google/uuid"
 )
@@ -21,36 +22,30 @@ func NewStore(db *sql.DB) *store {
 
 // GetCartByUserID is a method that retrieves the cart for a given user.
 // It returns a slice of cart and an error if any occurs during the retrieval process.
-func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error) {
-	querySelect := `
+func (s *store) GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error) {
+	q := query.Select(`
 		SELECT
-			*
+			id,
+			user_id,
+			product_id,
+			qty,
+			created_at,
+			updated_at,
+			deleted_at
 		FROM cart_items
-	`
-
-	var queryConditions []string
+	`).Where("deleted_at IS NULL")
 
 	if bReq.UserID != uuid.Nil {
-		queryConditions = append(queryConditions, fmt.Sprintf("user_id = '%s'", bReq.UserID))
+		q.Where("user_id = ?", bReq.UserID)
 	}
 
 	if len(bReq.ProductID) > 0 {
-		var productIDs []string
-		for _, pid := range bReq.ProductID {
-			productIDs = append(productIDs, fmt.Sprintf("'%s'", pid))
-		}
-		queryConditions = append(queryConditions, fmt.Sprintf("product_id IN (%s)", strings.Join(productIDs, ",")))
-	}
-
-	if len(queryConditions) > 0 {
-		querySelect += " WHERE " + strings.Join(queryConditions, " AND ")
-	} else {
-		querySelect += " WHERE deleted_at IS NULL"
+		q.Where("product_id = ANY(?)", query.UUIDs(bReq.ProductID))
 	}
 
-	querySelect += " AND deleted_at IS NULL"
+	querySelect, args := q.OrderBy("created_at", query.Asc).OrderBy("id", query.Asc).Build()
 
-	rows, err := s.db.Query(querySelect)
+	rows, err := repository.Conn(ctx, s.db).QueryContext(ctx, querySelect, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -80,14 +75,13 @@ func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error
 	return &carts, nil
 }
 
-func (s *store) AddCart(bReq model.Cart) (*uuid.UUID, error) {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-
+// AddCart is a method that adds a product to the active cart of a user.
+// If the user already has an active line for the product its qty is incremented instead,
+// and the returned flag reports whether a new line was created.
+func (s *store) AddCart(ctx context.Context, bReq model.Cart) (*uuid.UUID, bool, error) {
 	var id uuid.UUID
-	queryCreate := `
+	var created bool
+	queryUpsert := `
 		INSERT INTO cart_items (
 			user_id,
 			product_id,
@@ -98,92 +92,118 @@ func (s *store) AddCart(bReq model.Cart) (*uuid.UUID, error) {
 			$2,
 			$3,
 			NOW()
-		) RETURNING id
+		)
+		ON CONFLICT (user_id, product_id) WHERE deleted_at IS NULL
+		DO UPDATE SET
+			qty = cart_items.qty + EXCLUDED.qty,
+			updated_at = NOW()
+		RETURNING id, (xmax = 0) AS created
 	`
-	if err := tx.QueryRow(
-		queryCreate,
+	if err := repository.Conn(ctx, s.db).QueryRowContext(
+		ctx,
+		queryUpsert,
 		bReq.UserID,
 		bReq.ProductID,
 		bReq.Qty,
-	).Scan(&id); err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
-		return nil, err
+	).Scan(&id, &created); err != nil {
+		return nil, false, err
 	}
 
-	return &id, nil
+	return &id, created, nil
 }
 
-func (s *store) UpdateQty(userID, productID uuid.UUID, qty int) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-
+// LockCart is a method that locks the cart lines of a user until the transaction carried by ctx ends,
+// so that concurrent adds, qty updates and deletes of the cart wait for it.
+// It must be called inside a transaction.
+func (s *store) LockCart(ctx context.Context, userID uuid.UUID) error {
 	queryLock := `
 		SELECT 1
 		FROM cart_items
 		WHERE user_id = $1
 		FOR UPDATE
 	`
-	if _, err := tx.Exec(queryLock, userID); err != nil {
-		tx.Rollback()
-		return errors.New("failed to lock data")
+	if _, err := repository.Conn(ctx, s.db).ExecContext(ctx, queryLock, userID); err != nil {
+		return fmt.Errorf("failed to lock data: %w", err)
 	}
 
-	queryUpdate := `
-		UPDATE cart_items
-		SET qty = $1
-		WHERE user_id = $2 AND product_id = $3
-	`
-	if _, err := tx.Exec(queryUpdate, qty, userID, productID); err != nil {
-		tx.Rollback()
-		return errors.New("failed to update data")
-	}
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
-		return err
-	}
+// UpdateQty is a method that sets the qty of an active cart line.
+// It returns repository.ErrCartItemNotFound if the user has no active line for the product.
+func (s *store) UpdateQty(ctx context.Context, userID, productID uuid.UUID, qty int) error {
+	return repository.WithinTransaction(ctx, s.db, func(ctx context.Context) error {
+		tx := repository.Conn(ctx, s.db)
 
-	return nil
+		if err := s.LockCart(ctx, userID); err != nil {
+			return err
+		}
+
+		queryUpdate := `
+			UPDATE cart_items
+			SET qty = $1, updated_at = NOW()
+			WHERE user_id = $2 AND product_id = $3 AND deleted_at IS NULL
+		`
+		result, err := tx.ExecContext(ctx, queryUpdate, qty, userID, productID)
+		if err != nil {
+			return fmt.Errorf("failed to update data: %w", err)
+		}
+
+		return requireAffected(result)
+	})
 }
 
-func (s *store) DeleteProduct(bReq model.DeleteCartRequest) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
+// DeleteProduct is a method that soft-deletes an active cart line.
+// It returns repository.ErrCartItemNotFound if the user has no active line for the product.
+func (s *store) DeleteProduct(ctx context.Context, bReq model.DeleteCartRequest) error {
+	return repository.WithinTransaction(ctx, s.db, func(ctx context.Context) error {
+		tx := repository.Conn(ctx, s.db)
 
-	queryLock := `
-		SELECT 1
-		FROM cart_items
-		WHERE user_id = $1
-		FOR UPDATE
-	`
-	if _, err := tx.Exec(queryLock, bReq.UserID); err != nil {
-		tx.Rollback()
-		return errors.New("failed to lock data")
-	}
+		if err := s.LockCart(ctx, bReq.UserID); err != nil {
+			return err
+		}
+
+		queryUpdate := `
+			UPDATE cart_items
+			SET deleted_at = NOW()
+			WHERE user_id = $1 AND product_id = $2 AND deleted_at IS NULL
+		`
+		result, err := tx.ExecContext(ctx, queryUpdate, bReq.UserID, bReq.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to delete data: %w", err)
+		}
 
+		return requireAffected(result)
+	})
+}
+
+// DeleteProducts is a method that soft-deletes the active cart lines of a user for the given products.
+// It returns the number of cart lines that were deleted.
+func (s *store) DeleteProducts(ctx context.Context, userID uuid.UUID, productIDs []uuid.UUID) (int64, error) {
 	queryUpdate := `
 		UPDATE cart_items
 		SET deleted_at = NOW()
-		WHERE user_id = $1 AND product_id = $2
+		WHERE user_id = $1 AND product_id = ANY($2) AND deleted_at IS NULL
 	`
-	if _, err := tx.Exec(queryUpdate, bReq.UserID, bReq.ProductID); err != nil {
-		tx.Rollback()
-		return errors.New("failed to delete data")
+
+	result, err := repository.Conn(ctx, s.db).ExecContext(ctx, queryUpdate, userID, query.UUIDs(productIDs))
+	if err != nil {
+		return 0, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
+	return result.RowsAffected()
+}
+
+// requireAffected returns repository.ErrCartItemNotFound when a statement on a single cart line matched no row.
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
 
+	if affected == 0 {
+		return repository.ErrCartItemNotFound
+	}
+
 	return nil
 }
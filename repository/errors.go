@@ -0,0 +1,15 @@
+package repository
+
+import "cart-order-service/util/apperror"
+
+// Errors returned by every store implementation, so that callers can match them with errors.Is.
+var (
+	// ErrOrderNotFound is returned when an order does not exist or was deleted.
+	ErrOrderNotFound = apperror.NotFound("order not found")
+	// ErrOrderStatusChanged is returned when an order update expected a status the order is no longer in.
+	ErrOrderStatusChanged = apperror.Conflict("order status changed concurrently")
+	// ErrDuplicateTransaction is returned when a payment transaction ID is already recorded on another order.
+	ErrDuplicateTransaction = apperror.Conflict("payment transaction already recorded on another order")
+	// ErrCartItemNotFound is returned when a user has no active cart line for a product.
+	ErrCartItemNotFound = apperror.NotFound("cart item not found")
+)
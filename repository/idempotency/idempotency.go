@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"cart-order-service/repository"
+	model "cart-order-service/repository/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB) *store {
+	return &store{db}
+}
+
+// Reserve is a method that claims an idempotency key for a new request.
+// If the key was already used in the same scope it returns the stored key and false instead.
+// A reservation that is still unfinished after staleAfter was abandoned by a crashed request and is taken over.
+func (s *store) Reserve(ctx context.Context, bReq model.IdempotencyKey, staleAfter time.Duration) (*model.IdempotencyKey, bool, error) {
+	queryCreate := `
+		INSERT INTO idempotency_keys (
+			scope,
+			key,
+			request_hash,
+			created_at
+		) VALUES (
+			$1, $2, $3, NOW()
+		)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			created_at = EXCLUDED.created_at
+		WHERE idempotency_keys.completed_at IS NULL
+			AND idempotency_keys.created_at < NOW() - make_interval(secs => $4)
+		RETURNING created_at
+	`
+
+	err := repository.Conn(ctx, s.db).QueryRowContext(
+		ctx,
+		queryCreate,
+		bReq.Scope,
+		bReq.Key,
+		bReq.RequestHash,
+		staleAfter.Seconds(),
+	).Scan(&bReq.CreatedAt)
+	if err == nil {
+		return &bReq, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	querySelect := `
+		SELECT
+			scope,
+			key,
+			request_hash,
+			COALESCE(status_code, 0),
+			response_body,
+			created_at,
+			completed_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`
+
+	var existing model.IdempotencyKey
+	if err := repository.Conn(ctx, s.db).QueryRowContext(ctx, querySelect, bReq.Scope, bReq.Key).Scan(
+		&existing.Scope,
+		&existing.Key,
+		&existing.RequestHash,
+		&existing.StatusCode,
+		&existing.ResponseBody,
+		&existing.CreatedAt,
+		&existing.CompletedAt,
+	); err != nil {
+		return nil, false, err
+	}
+
+	return &existing, false, nil
+}
+
+// Complete is a method that records the response of the request that reserved an idempotency key.
+// reservedAt is the creation time returned by Reserve: once another request has taken the key over, it no longer
+// matches and the response is dropped.
+func (s *store) Complete(ctx context.Context, scope, key string, reservedAt time.Time, statusCode int, body []byte) error {
+	queryUpdate := `
+		UPDATE idempotency_keys SET
+			status_code = $1,
+			response_body = $2,
+			completed_at = NOW()
+		WHERE scope = $3 AND key = $4 AND created_at = $5
+	`
+
+	_, err := repository.Conn(ctx, s.db).ExecContext(ctx, queryUpdate, statusCode, body, scope, key, reservedAt)
+	return err
+}
+
+// Release is a method that removes an unfinished reservation so the key can be retried.
+// Like Complete, it leaves the key alone once another request has taken it over.
+func (s *store) Release(ctx context.Context, scope, key string, reservedAt time.Time) error {
+	queryDelete := `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND created_at = $3 AND completed_at IS NULL
+	`
+
+	_, err := repository.Conn(ctx, s.db).ExecContext(ctx, queryDelete, scope, key, reservedAt)
+	return err
+}
+
+// DeleteExpired is a method that removes the idempotency keys older than ttl.
+// The cutoff is computed by the database, in the same clock and time zone created_at was written with.
+// It returns the number of keys that were removed.
+func (s *store) DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error) {
+	queryDelete := `
+		DELETE FROM idempotency_keys
+		WHERE created_at < NOW() - make_interval(secs => $1)
+	`
+
+	result, err := repository.Conn(ctx, s.db).ExecContext(ctx, queryDelete, ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,132 @@
+// Package query is a small SQL builder shared by the Postgres stores.
+// Conditions are written with ? placeholders that are rewritten to positional $n arguments,
+// so values are always sent as query parameters instead of being formatted into the SQL text.
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Direction is the sort direction of an ORDER BY expression.
+type Direction string
+
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+// Builder composes a SELECT statement from a base query, filters, sorting and pagination.
+type Builder struct {
+	base       string
+	conditions []string
+	orderBy    []string
+	suffix     string
+	args       []any
+	limit      int
+	offset     int
+}
+
+// Select is a constructor function that returns a new Builder for the given base query,
+// which must not contain a WHERE clause.
+func Select(base string) *Builder {
+	return &Builder{base: base}
+}
+
+// Where adds a condition joined to the others with AND.
+// Every ? in cond is replaced by the positional argument for the matching value;
+// use "column = ANY(?)" together with UUIDs or pq.Array to filter on a list.
+func (b *Builder) Where(cond string, args ...any) *Builder {
+	if strings.Count(cond, "?") != len(args) {
+		panic(fmt.Sprintf("query: condition %q expects %d arguments, got %d", cond, strings.Count(cond, "?"), len(args)))
+	}
+
+	var sb strings.Builder
+	for _, arg := range args {
+		before, after, _ := strings.Cut(cond, "?")
+		sb.WriteString(before)
+		sb.WriteString(b.bind(arg))
+		cond = after
+	}
+	sb.WriteString(cond)
+
+	b.conditions = append(b.conditions, sb.String())
+	return b
+}
+
+// OrderBy adds a sort expression; expressions are applied in the order they were added.
+func (b *Builder) OrderBy(column string, direction Direction) *Builder {
+	if direction != Asc && direction != Desc {
+		direction = Asc
+	}
+
+	b.orderBy = append(b.orderBy, column+" "+string(direction))
+	return b
+}
+
+// Limit caps the number of returned rows; zero means no limit.
+func (b *Builder) Limit(limit int) *Builder {
+	b.limit = limit
+	return b
+}
+
+// Offset skips the given number of rows.
+func (b *Builder) Offset(offset int) *Builder {
+	b.offset = offset
+	return b
+}
+
+// Suffix appends raw SQL after the pagination clauses, such as FOR UPDATE.
+func (b *Builder) Suffix(suffix string) *Builder {
+	b.suffix = suffix
+	return b
+}
+
+// Build returns the SQL statement and its positional arguments.
+func (b *Builder) Build() (string, []any) {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(b.base))
+
+	if len(b.conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.conditions, " AND "))
+	}
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+
+	if b.limit > 0 {
+		sb.WriteString(" LIMIT " + b.bind(b.limit))
+	}
+
+	if b.offset > 0 {
+		sb.WriteString(" OFFSET " + b.bind(b.offset))
+	}
+
+	if b.suffix != "" {
+		sb.WriteString(" " + b.suffix)
+	}
+
+	return sb.String(), b.args
+}
+
+// bind records arg as the next positional argument and returns its placeholder.
+func (b *Builder) bind(arg any) string {
+	b.args = append(b.args, arg)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// UUIDs wraps a list of UUIDs as a Postgres array argument, for use with ANY(?).
+func UUIDs(ids []uuid.UUID) any {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+
+	return pq.Array(values)
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"cart-order-service/repository"
+	model "cart-order-service/repository/models"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// cartStore is the in-memory counterpart of the cart_items store.
+type cartStore struct {
+	db *DB
+}
+
+// NewCartStore is a constructor function that returns a new cart store backed by db.
+func NewCartStore(db *DB) *cartStore {
+	return &cartStore{db}
+}
+
+// GetCartByUserID is a method that retrieves the active cart lines matching the request filters, oldest first.
+func (s *cartStore) GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error) {
+	var carts []model.Cart
+	err := s.db.run(ctx, func(d *data) error {
+		for _, cart := range d.carts {
+			if cart.DeletedAt != nil {
+				continue
+			}
+			if bReq.UserID != uuid.Nil && cart.UserID != bReq.UserID {
+				continue
+			}
+			if len(bReq.ProductID) > 0 && !slices.Contains(bReq.ProductID, cart.ProductID) {
+				continue
+			}
+			carts = append(carts, cart)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &carts, nil
+}
+
+// AddCart is a method that adds a product to the active cart of a user,
+// incrementing the qty of the active line for the same product if there is one.
+// The returned flag reports whether a new line was created.
+func (s *cartStore) AddCart(ctx context.Context, bReq model.Cart) (*uuid.UUID, bool, error) {
+	var id uuid.UUID
+	var created bool
+	err := s.db.run(ctx, func(d *data) error {
+		if i := activeCartLine(d, bReq.UserID, bReq.ProductID); i >= 0 {
+			cart := d.carts[i]
+			cart.Qty += bReq.Qty
+			cart.UpdatedAt = now()
+			d.carts[i] = cart
+
+			id = cart.ID
+			return nil
+		}
+
+		id, created = uuid.New(), true
+		d.carts = append(d.carts, model.Cart{
+			ID:        id,
+			UserID:    bReq.UserID,
+			ProductID: bReq.ProductID,
+			Qty:       bReq.Qty,
+			CreatedAt: now(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &id, created, nil
+}
+
+// LockCart is a method that mirrors the row lock of the Postgres store.
+// Transactions already hold the whole database, so there is nothing left to lock.
+func (s *cartStore) LockCart(ctx context.Context, userID uuid.UUID) error {
+	return s.db.run(ctx, func(d *data) error {
+		return nil
+	})
+}
+
+// UpdateQty is a method that sets the qty of an active cart line.
+// It returns repository.ErrCartItemNotFound if the user has no active line for the product.
+func (s *cartStore) UpdateQty(ctx context.Context, userID, productID uuid.UUID, qty int) error {
+	return s.db.run(ctx, func(d *data) error {
+		i := activeCartLine(d, userID, productID)
+		if i < 0 {
+			return repository.ErrCartItemNotFound
+		}
+
+		cart := d.carts[i]
+		cart.Qty = qty
+		cart.UpdatedAt = now()
+		d.carts[i] = cart
+		return nil
+	})
+}
+
+// DeleteProduct is a method that soft-deletes an active cart line.
+// It returns repository.ErrCartItemNotFound if the user has no active line for the product.
+func (s *cartStore) DeleteProduct(ctx context.Context, bReq model.DeleteCartRequest) error {
+	return s.db.run(ctx, func(d *data) error {
+		i := activeCartLine(d, bReq.UserID, bReq.ProductID)
+		if i < 0 {
+			return repository.ErrCartItemNotFound
+		}
+
+		cart := d.carts[i]
+		cart.DeletedAt = now()
+		d.carts[i] = cart
+		return nil
+	})
+}
+
+// DeleteProducts is a method that soft-deletes the active cart lines of a user for the given products.
+// It returns the number of cart lines that were deleted.
+func (s *cartStore) DeleteProducts(ctx context.Context, userID uuid.UUID, productIDs []uuid.UUID) (int64, error) {
+	var deleted int64
+	err := s.db.run(ctx, func(d *data) error {
+		for _, productID := range productIDs {
+			i := activeCartLine(d, userID, productID)
+			if i < 0 {
+				continue
+			}
+
+			cart := d.carts[i]
+			cart.DeletedAt = now()
+			d.carts[i] = cart
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// activeCartLine returns the index of the active cart line of a user for a product, or -1 if there is none.
+// There is at most one, like the partial unique index on cart_items guarantees.
+func activeCartLine(d *data, userID, productID uuid.UUID) int {
+	return slices.IndexFunc(d.carts, func(cart model.Cart) bool {
+		return cart.DeletedAt == nil && cart.UserID == userID && cart.ProductID == productID
+	})
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	model "cart-order-service/repository/models"
+
+	"github.com/google/uuid"
+)
+
+// txKey is the context key under which the DB running the current transaction is stored.
+type txKey struct{}
+
+// DB is an in-memory database shared by the memory stores.
+// It is safe for concurrent use: every store call holds its lock, and a transaction holds it
+// from start to end, so transactions are serializable and a failed one is rolled back from a snapshot.
+type DB struct {
+	mu   sync.Mutex
+	data *data
+}
+
+// data is the content of a DB. Stored values are never modified in place, so that a shallow
+// copy of the collections is enough to snapshot it.
+type data struct {
+	carts  []model.Cart
+	orders []model.Order
+	logs   []model.OrderItemsLogs
+	keys   map[idempotencyID]model.IdempotencyKey
+	prices map[uuid.UUID]model.ProductPrice
+}
+
+// NewDB is a constructor function that returns a new empty DB.
+func NewDB() *DB {
+	return &DB{data: &data{
+		keys:   map[idempotencyID]model.IdempotencyKey{},
+		prices: map[uuid.UUID]model.ProductPrice{},
+	}}
+}
+
+// WithinTransaction runs fn inside a single transaction carried by the context passed to fn,
+// with the same semantics as repository.Transactor: it is rolled back when fn returns an error,
+// and fn joins the transaction ctx already carries, if any.
+func (db *DB) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if db.inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	snapshot := db.data.clone()
+	if err := fn(context.WithValue(ctx, txKey{}, db)); err != nil {
+		db.data = snapshot
+		return err
+	}
+
+	return nil
+}
+
+// run calls fn with the DB content, holding the lock unless ctx carries a transaction of this DB,
+// which already holds it. The context error is returned first, like a cancelled query would.
+func (db *DB) run(ctx context.Context, fn func(d *data) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if db.inTransaction(ctx) {
+		return fn(db.data)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return fn(db.data)
+}
+
+func (db *DB) inTransaction(ctx context.Context) bool {
+	tx, _ := ctx.Value(txKey{}).(*DB)
+	return tx == db
+}
+
+func (d *data) clone() *data {
+	return &data{
+		carts:  slices.Clone(d.carts),
+		orders: slices.Clone(d.orders),
+		logs:   slices.Clone(d.logs),
+		keys:   maps.Clone(d.keys),
+		prices: maps.Clone(d.prices),
+	}
+}
+
+// now returns the current time with the microsecond precision of Postgres timestamps.
+func now() *time.Time {
+	t := time.Now().UTC().Truncate(time.Microsecond)
+	return &t
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"time"
+)
+
+// idempotencyID is the primary key of a stored idempotency key.
+type idempotencyID struct {
+	scope string
+	key   string
+}
+
+// idempotencyStore is the in-memory counterpart of the idempotency_keys store.
+type idempotencyStore struct {
+	db *DB
+}
+
+// NewIdempotencyStore is a constructor function that returns a new idempotency key store backed by db.
+func NewIdempotencyStore(db *DB) *idempotencyStore {
+	return &idempotencyStore{db}
+}
+
+// Reserve is a method that claims an idempotency key for a new request.
+// If the key was already used in the same scope it returns the stored key and false instead.
+// A reservation that is still unfinished after staleAfter was abandoned by a crashed request and is taken over.
+func (s *idempotencyStore) Reserve(ctx context.Context, bReq model.IdempotencyKey, staleAfter time.Duration) (*model.IdempotencyKey, bool, error) {
+	var reserved model.IdempotencyKey
+	var created bool
+	err := s.db.run(ctx, func(d *data) error {
+		id := idempotencyID{bReq.Scope, bReq.Key}
+		existing, ok := d.keys[id]
+		if ok && (existing.CompletedAt != nil || !existing.CreatedAt.Before(now().Add(-staleAfter))) {
+			reserved = existing
+			return nil
+		}
+
+		reserved = model.IdempotencyKey{
+			Scope:       bReq.Scope,
+			Key:         bReq.Key,
+			RequestHash: bReq.RequestHash,
+			CreatedAt:   now(),
+		}
+		d.keys[id] = reserved
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &reserved, created, nil
+}
+
+// Complete is a method that records the response of the request that reserved an idempotency key.
+// reservedAt is the creation time returned by Reserve: once another request has taken the key over, it no longer
+// matches and the response is dropped.
+func (s *idempotencyStore) Complete(ctx context.Context, scope, key string, reservedAt time.Time, statusCode int, body []byte) error {
+	return s.db.run(ctx, func(d *data) error {
+		id := idempotencyID{scope, key}
+		reserved, ok := d.keys[id]
+		if !ok || !reserved.CreatedAt.Equal(reservedAt) {
+			return nil
+		}
+
+		reserved.StatusCode = statusCode
+		reserved.ResponseBody = bytes.Clone(body)
+		reserved.CompletedAt = now()
+		d.keys[id] = reserved
+		return nil
+	})
+}
+
+// Release is a method that removes an unfinished reservation so the key can be retried.
+// Like Complete, it leaves the key alone once another request has taken it over.
+func (s *idempotencyStore) Release(ctx context.Context, scope, key string, reservedAt time.Time) error {
+	return s.db.run(ctx, func(d *data) error {
+		id := idempotencyID{scope, key}
+		if reserved, ok := d.keys[id]; ok && reserved.CompletedAt == nil && reserved.CreatedAt.Equal(reservedAt) {
+			delete(d.keys, id)
+		}
+		return nil
+	})
+}
+
+// DeleteExpired is a method that removes the idempotency keys older than ttl.
+// It returns the number of keys that were removed.
+func (s *idempotencyStore) DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error) {
+	var deleted int64
+	err := s.db.run(ctx, func(d *data) error {
+		before := now().Add(-ttl)
+		for id, reserved := range d.keys {
+			if reserved.CreatedAt.Before(before) {
+				delete(d.keys, id)
+				deleted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
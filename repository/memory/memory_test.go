@@ -0,0 +1,20 @@
+package memory_test
+
+import (
+	"cart-order-service/repository/memory"
+	"cart-order-service/repository/storetest"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		db := memory.NewDB()
+
+		return storetest.Stores{
+			Cart:       memory.NewCartStore(db),
+			Order:      memory.NewOrderStore(db),
+			Product:    memory.NewProductStore(db),
+			Transactor: db,
+		}
+	})
+}
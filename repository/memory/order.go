@@ -0,0 +1,221 @@
+package memory
+
+import (
+	"bytes"
+	"cart-order-service/repository"
+	model "cart-order-service/repository/models"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// orderStore is the in-memory counterpart of the orders and order_status_logs store.
+type orderStore struct {
+	db *DB
+}
+
+// NewOrderStore is a constructor function that returns a new order store backed by db.
+func NewOrderStore(db *DB) *orderStore {
+	return &orderStore{db}
+}
+
+// CreateOrder is a method that creates a new order and returns the order ID and ref code.
+func (o *orderStore) CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, *string, error) {
+	order := bReq
+	order.ID = uuid.New()
+	order.ProductOrder = bytes.Clone(bReq.ProductOrder)
+	order.TransactionID = ""
+	order.CreatedAt = now()
+	order.UpdatedAt = nil
+	order.DeletedAt = nil
+
+	err := o.db.run(ctx, func(d *data) error {
+		d.orders = append(d.orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &order.ID, &order.RefCode, nil
+}
+
+// CreateOrderItemsLogs is a method that appends an entry to the status history of an order.
+func (o *orderStore) CreateOrderItemsLogs(ctx context.Context, bReq model.OrderItemsLogs) (*string, error) {
+	log := bReq
+	log.CreatedAt = now()
+
+	err := o.db.run(ctx, func(d *data) error {
+		d.logs = append(d.logs, log)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &log.RefCode, nil
+}
+
+// GetOrderStatus is a method that returns the current status of an order.
+// It returns repository.ErrOrderNotFound if the order does not exist.
+func (o *orderStore) GetOrderStatus(ctx context.Context, orderID uuid.UUID) (string, error) {
+	var status string
+	err := o.db.run(ctx, func(d *data) error {
+		i := activeOrder(d, orderID)
+		if i < 0 {
+			return repository.ErrOrderNotFound
+		}
+
+		status = d.orders[i].Status
+		return nil
+	})
+
+	return status, err
+}
+
+// UpdateOrder is a method that moves an order from fromStatus to the status in the request.
+// It returns repository.ErrOrderStatusChanged if the order does not exist or is no longer in fromStatus,
+// and repository.ErrDuplicateTransaction if the transaction ID was already recorded on another order.
+func (o *orderStore) UpdateOrder(ctx context.Context, bReq model.UpdateRequest, fromStatus string) (*string, error) {
+	var refCode string
+	err := o.db.run(ctx, func(d *data) error {
+		i := slices.IndexFunc(d.orders, func(order model.Order) bool {
+			return order.ID == bReq.OrderID && order.Status == fromStatus
+		})
+		if i < 0 {
+			return repository.ErrOrderStatusChanged
+		}
+
+		if bReq.TransactionID != "" && slices.ContainsFunc(d.orders, func(order model.Order) bool {
+			return order.ID != bReq.OrderID && order.TransactionID == bReq.TransactionID
+		}) {
+			return repository.ErrDuplicateTransaction
+		}
+
+		order := d.orders[i]
+		order.Status = bReq.Status
+		order.IsPaid = order.IsPaid || bReq.IsPaid
+		if bReq.TransactionID != "" {
+			order.TransactionID = bReq.TransactionID
+		}
+		order.UpdatedAt = now()
+		d.orders[i] = order
+
+		refCode = order.RefCode
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &refCode, nil
+}
+
+// GetOrder is a method that retrieves a single order by its ID.
+// It returns repository.ErrOrderNotFound if the order does not exist.
+func (o *orderStore) GetOrder(ctx context.Context, orderID uuid.UUID) (*model.Order, error) {
+	var order model.Order
+	err := o.db.run(ctx, func(d *data) error {
+		i := activeOrder(d, orderID)
+		if i < 0 {
+			return repository.ErrOrderNotFound
+		}
+
+		order = d.orders[i]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// ListOrders is a method that retrieves a page of orders matching the request filters.
+// Orders are sorted by created_at and ID, and the page starts right after the cursor position if one is set.
+func (o *orderStore) ListOrders(ctx context.Context, bReq model.ListOrdersRequest) ([]model.Order, error) {
+	orders := []model.Order{}
+	err := o.db.run(ctx, func(d *data) error {
+		for _, order := range d.orders {
+			if order.DeletedAt != nil {
+				continue
+			}
+			if bReq.UserID != uuid.Nil && order.UserID != bReq.UserID {
+				continue
+			}
+			if bReq.Status != "" && order.Status != bReq.Status {
+				continue
+			}
+			if bReq.From != nil && order.CreatedAt.Before(*bReq.From) {
+				continue
+			}
+			if bReq.To != nil && !order.CreatedAt.Before(*bReq.To) {
+				continue
+			}
+			orders = append(orders, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	desc := bReq.Sort != "asc"
+	slices.SortFunc(orders, func(a, b model.Order) int {
+		c := compareOrderPosition(a, *b.CreatedAt, b.ID)
+		if desc {
+			return -c
+		}
+		return c
+	})
+
+	if bReq.CursorCreatedAt != nil {
+		orders = slices.DeleteFunc(orders, func(order model.Order) bool {
+			c := compareOrderPosition(order, *bReq.CursorCreatedAt, bReq.CursorID)
+			return (desc && c >= 0) || (!desc && c <= 0)
+		})
+	}
+
+	if bReq.Limit > 0 && len(orders) > bReq.Limit {
+		orders = orders[:bReq.Limit]
+	}
+
+	return orders, nil
+}
+
+// GetOrderStatusLogs is a method that retrieves the status history of an order, oldest entry first.
+func (o *orderStore) GetOrderStatusLogs(ctx context.Context, orderID uuid.UUID) ([]model.OrderItemsLogs, error) {
+	logs := []model.OrderItemsLogs{}
+	err := o.db.run(ctx, func(d *data) error {
+		for _, log := range d.logs {
+			if log.OrderID == orderID {
+				logs = append(logs, log)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// activeOrder returns the index of the order with the given ID unless it is deleted, or -1.
+func activeOrder(d *data, orderID uuid.UUID) int {
+	return slices.IndexFunc(d.orders, func(order model.Order) bool {
+		return order.ID == orderID && order.DeletedAt == nil
+	})
+}
+
+// compareOrderPosition compares the (created_at, id) position of an order with the given one,
+// the same way Postgres compares the row values used for keyset pagination.
+func compareOrderPosition(order model.Order, createdAt time.Time, id uuid.UUID) int {
+	if c := order.CreatedAt.Compare(createdAt); c != 0 {
+		return c
+	}
+
+	return bytes.Compare(order.ID[:], id[:])
+}
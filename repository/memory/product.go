@@ -0,0 +1,55 @@
+package memory
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// productStore is the in-memory counterpart of the product_prices store.
+type productStore struct {
+	db *DB
+}
+
+// NewProductStore is a constructor function that returns a new product price store backed by db.
+func NewProductStore(db *DB) *productStore {
+	return &productStore{db}
+}
+
+// GetPrices is a method that retrieves the unit prices of the given products.
+// Products without a price are missing from the returned map.
+func (s *productStore) GetPrices(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	prices := make(map[uuid.UUID]float64, len(productIDs))
+	err := s.db.run(ctx, func(d *data) error {
+		for _, productID := range productIDs {
+			if price, ok := d.prices[productID]; ok {
+				prices[productID] = price.Price
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// SetPrice is a method that creates or replaces the unit price of a product.
+func (s *productStore) SetPrice(ctx context.Context, bReq model.ProductPrice) error {
+	return s.db.run(ctx, func(d *data) error {
+		price := model.ProductPrice{
+			ProductID: bReq.ProductID,
+			Price:     bReq.Price,
+			CreatedAt: now(),
+		}
+		if existing, ok := d.prices[bReq.ProductID]; ok {
+			price.CreatedAt = existing.CreatedAt
+			price.UpdatedAt = now()
+		}
+
+		d.prices[bReq.ProductID] = price
+		return nil
+	})
+}
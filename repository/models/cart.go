@@ -16,6 +16,12 @@ type Cart struct {
 	DeletedAt *time.Time `json:"deleted_at"`
 }
 
+// AddCartResponse tells whether adding a product created a new cart line or was merged into an existing one.
+type AddCartResponse struct {
+	ID      uuid.UUID `json:"id"`
+	Created bool      `json:"created"`
+}
+
 type GetCartRequest struct {
 	UserID    uuid.UUID   `json:"user_id"`
 	ProductID []uuid.UUID `json:"product_id"`
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// IdempotencyKey is a client supplied Idempotency-Key together with the request it was first used for
+// and, once that request finished, the response to replay for retries.
+// StatusCode is zero while the original request is still being processed.
+type IdempotencyKey struct {
+	Scope        string     `json:"scope"`
+	Key          string     `json:"key"`
+	RequestHash  string     `json:"request_hash"`
+	StatusCode   int        `json:"status_code"`
+	ResponseBody []byte     `json:"response_body"`
+	CreatedAt    *time.Time `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
@@ -9,15 +9,52 @@ import (
 
 var (
 	OrderStatusPending    = "pending"
+	OrderStatusPaid       = "paid"
 	OrderStatusProcessing = "processing"
+	OrderStatusPacking    = "packing"
+	OrderStatusPickup     = "pickup"
 	OrderStatusCompleted  = "completed"
 	OrderStatusCancelled  = "cancelled"
-	OrderStatusPakcing    = "packing"
-	OrderStatusPaid       = "paid"
-	OrderStatusPacking    = "pickup"
 )
 
+// Actors recorded in order_status_logs for transitions that are not made by a user.
+// Transitions made by a user record the user ID instead.
+var (
+	OrderActorSystem         = "system"
+	OrderActorPaymentGateway = "payment_gateway"
+)
+
+// orderStatusTransitions lists, for every order status, the statuses an order may move to next.
+// Completed and cancelled orders are final and cannot transition any further.
+var orderStatusTransitions = map[string][]string{
+	OrderStatusPending:    {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:       {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusPacking, OrderStatusCancelled},
+	OrderStatusPacking:    {OrderStatusPickup},
+	OrderStatusPickup:     {OrderStatusCompleted},
+	OrderStatusCompleted:  {},
+	OrderStatusCancelled:  {},
+}
+
+// IsValidOrderStatus reports whether status is one of the known order statuses.
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionOrderStatus reports whether an order in status from may be moved to status to.
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Order struct {
+	ID            uuid.UUID       `json:"id"`
 	UserID        uuid.UUID       `json:"user_id" validate:"required"`
 	PaymentTypeID uuid.UUID       `json:"payment_type_id" validate:"required"`
 	OrderNumber   string          `json:"order_number" validate:"required"`
@@ -26,6 +63,7 @@ type Order struct {
 	Status        string          `json:"status" validate:"required"`
 	IsPaid        bool            `json:"is_paid"`
 	RefCode       string          `json:"ref_code"`
+	TransactionID string          `json:"transaction_id,omitempty"`
 	CreatedAt     *time.Time      `json:"created_at"`
 	UpdatedAt     *time.Time      `json:"updated_at"`
 	DeletedAt     *time.Time      `json:"deleted_at"`
@@ -37,12 +75,65 @@ type OrderItemsLogs struct {
 	FromStatus string     `json:"from_status"`
 	ToStatus   string     `json:"to_status"`
 	Notes      string     `json:"notes"`
+	Actor      string     `json:"actor"`
 	CreatedAt  *time.Time `json:"created_at"`
 }
 
+// OrderDetail is an order together with its status history, oldest entry first.
+type OrderDetail struct {
+	Order
+	StatusHistory []OrderItemsLogs `json:"status_history"`
+}
+
+// ListOrdersRequest holds the filters and keyset pagination parameters for listing orders.
+// Orders are sorted by created_at, then by ID to break ties; Sort is either "asc" or "desc".
+type ListOrdersRequest struct {
+	UserID          uuid.UUID
+	Status          string
+	From            *time.Time
+	To              *time.Time
+	Sort            string
+	Limit           int
+	Cursor          string
+	CursorCreatedAt *time.Time
+	CursorID        uuid.UUID
+}
+
+type ListOrdersResponse struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// ProductOrder is a single order line stored in the product_order column of an order.
+type ProductOrder struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+	Price     float64   `json:"price"`
+}
+
+// CheckoutRequest holds the data needed to turn a user's active cart into an order.
+type CheckoutRequest struct {
+	UserID        uuid.UUID `json:"-"`
+	PaymentTypeID uuid.UUID `json:"payment_type_id" validate:"required"`
+	OrderNumber   string    `json:"-"`
+	RefCode       string    `json:"-"`
+}
+
+type CheckoutResponse struct {
+	OrderID uuid.UUID `json:"order_id"`
+	RefCode string    `json:"ref_code"`
+}
+
+// UpdateRequest moves an order to a new status.
+// TransactionID is the payment gateway transaction that paid the order, required when moving to paid.
+// Notes replaces the default note recorded in the status log.
+// IsPaid is not read from clients, the usecase sets it when the order moves to paid.
 type UpdateRequest struct {
-	OrderID   uuid.UUID  `json:"order_id" validate:"required"`
-	Status    string     `json:"status" validate:"required"`
-	IsPaid    bool       `json:"is_paid"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	OrderID       uuid.UUID  `json:"order_id" validate:"required"`
+	Status        string     `json:"status" validate:"required"`
+	IsPaid        bool       `json:"-"`
+	TransactionID string     `json:"transaction_id"`
+	Notes         string     `json:"notes"`
+	Actor         string     `json:"-"`
+	UpdatedAt     *time.Time `json:"updated_at"`
 }
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductPrice is the unit price checkout charges for a product.
+type ProductPrice struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	Price     float64    `json:"price" validate:"gt=0"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
@@ -1,12 +1,42 @@
 package order
 
 import (
+	"cart-order-service/repository"
+	"cart-order-service/repository/internal/query"
 	model "cart-order-service/repository/models"
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// uniqueViolation is the Postgres error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// orderColumns is the column list selected by every order query, in scanOrder order.
+const orderColumns = `
+	id,
+	user_id,
+	payment_type_id,
+	order_number,
+	total_price,
+	product_order,
+	status,
+	is_paid,
+	ref_code,
+	payment_transaction_id,
+	created_at,
+	updated_at,
+	deleted_at
+`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 type store struct {
 	db *sql.DB
 }
@@ -17,12 +47,7 @@ func NewStore(db *sql.DB) *store {
 
 // CreateOrder is a method that creates a new order and returns the order ID.
 // It returns an error if any occurs during the creation process.
-func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
-	tx, err := o.db.Begin()
-	if err != nil {
-		return nil, nil, err
-	}
-
+func (o *store) CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, *string, error) {
 	queryCreate := `
 		INSERT INTO orders (
 			user_id,
@@ -41,7 +66,8 @@ func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 
 	var orderID uuid.UUID
 	var refCode string
-	if err := tx.QueryRow(
+	if err := repository.Conn(ctx, o.db).QueryRowContext(
+		ctx,
 		queryCreate,
 		bReq.UserID,
 		bReq.PaymentTypeID,
@@ -52,12 +78,6 @@ func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 		bReq.IsPaid,
 		bReq.RefCode,
 	).Scan(&orderID, &refCode); err != nil {
-		tx.Rollback()
-		return nil, nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
 		return nil, nil, err
 	}
 
@@ -66,12 +86,7 @@ func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 
 // createOrderItemsLogs is a method that creates a new order items log.
 // It returns an error if any occurs during the creation process.
-func (o *store) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error) {
-	tx, err := o.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-
+func (o *store) CreateOrderItemsLogs(ctx context.Context, bReq model.OrderItemsLogs) (*string, error) {
 	queryCreate := `
 		INSERT INTO order_status_logs (
 			order_id,
@@ -79,62 +94,246 @@ func (o *store) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
 			from_status,
 			to_status,
 			notes,
+			actor,
 			created_at
 		) VALUES (
-			$1, $2, $3, $4, $5, NOW()
+			$1, $2, $3, $4, $5, $6, NOW()
 		) RETURNING ref_code
 	`
 
 	var refCode string
-	if err := tx.QueryRow(
+	if err := repository.Conn(ctx, o.db).QueryRowContext(
+		ctx,
 		queryCreate,
 		bReq.OrderID,
 		bReq.RefCode,
 		bReq.FromStatus,
 		bReq.ToStatus,
 		bReq.Notes,
+		bReq.Actor,
 	).Scan(&refCode); err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
 	return &refCode, nil
 }
 
-func (o *store) UpdateOrder(bReq model.UpdateRequest) (*string, error) {
-	tx, err := o.db.Begin()
+// GetOrderStatus is a method that returns the current status of an order.
+// When called inside a transaction the order row stays locked until the transaction ends.
+// It returns sql.ErrNoRows if the order does not exist.
+func (o *store) GetOrderStatus(ctx context.Context, orderID uuid.UUID) (string, error) {
+	querySelect := `
+		SELECT status
+		FROM orders
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	var status string
+	err := repository.Conn(ctx, o.db).QueryRowContext(ctx, querySelect, orderID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", repository.ErrOrderNotFound.Wrap(err)
+	}
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
+	return status, nil
+}
+
+// UpdateOrder is a method that moves an order from fromStatus to the status in the request.
+// The update only applies while the order is still in fromStatus, so it returns repository.ErrOrderStatusChanged
+// if the order does not exist or its status was changed concurrently.
+// It returns repository.ErrDuplicateTransaction if the transaction ID was already recorded on another order.
+func (o *store) UpdateOrder(ctx context.Context, bReq model.UpdateRequest, fromStatus string) (*string, error) {
 	queryUpdate := `
 		UPDATE orders SET
 			status = $1,
-			is_paid = $2, 
+			is_paid = is_paid OR $2,
+			payment_transaction_id = COALESCE(NULLIF($5, ''), payment_transaction_id),
 			updated_at = NOW()
-		WHERE id = $3 RETURNING ref_code
+		WHERE id = $3 AND status = $4 RETURNING ref_code
 	`
 
 	var refCode string
-	if err := tx.QueryRow(
+	err := repository.Conn(ctx, o.db).QueryRowContext(
+		ctx,
 		queryUpdate,
 		bReq.Status,
 		bReq.IsPaid,
 		bReq.OrderID,
-	).Scan(&refCode); err != nil {
-		tx.Rollback()
+		fromStatus,
+		bReq.TransactionID,
+	).Scan(&refCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrOrderStatusChanged.Wrap(err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return nil, repository.ErrDuplicateTransaction.Wrap(err)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
+	return &refCode, nil
+}
+
+// GetOrder is a method that retrieves a single order by its ID.
+// It returns repository.ErrOrderNotFound if the order does not exist.
+func (o *store) GetOrder(ctx context.Context, orderID uuid.UUID) (*model.Order, error) {
+	querySelect := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	order, err := scanOrder(repository.Conn(ctx, o.db).QueryRowContext(ctx, querySelect, orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrOrderNotFound.Wrap(err)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	return &refCode, nil
+	return order, nil
+}
+
+// ListOrders is a method that retrieves a page of orders matching the request filters.
+// Orders are sorted by created_at and ID, and the page starts right after the cursor position if one is set.
+func (o *store) ListOrders(ctx context.Context, bReq model.ListOrdersRequest) ([]model.Order, error) {
+	q := query.Select(`
+		SELECT ` + orderColumns + `
+		FROM orders
+	`).Where("deleted_at IS NULL")
+
+	if bReq.UserID != uuid.Nil {
+		q.Where("user_id = ?", bReq.UserID)
+	}
+
+	if bReq.Status != "" {
+		q.Where("status = ?", bReq.Status)
+	}
+
+	// created_at has no time zone and holds the session's local time, so the bounds are converted
+	// from instants to that time zone by the database itself.
+	if bReq.From != nil {
+		q.Where("created_at >= (?::timestamptz AT TIME ZONE current_setting('TimeZone'))", *bReq.From)
+	}
+
+	if bReq.To != nil {
+		q.Where("created_at < (?::timestamptz AT TIME ZONE current_setting('TimeZone'))", *bReq.To)
+	}
+
+	direction, comparator := query.Desc, "<"
+	if bReq.Sort == "asc" {
+		direction, comparator = query.Asc, ">"
+	}
+
+	if bReq.CursorCreatedAt != nil {
+		q.Where("(created_at, id) "+comparator+" (?, ?)", *bReq.CursorCreatedAt, bReq.CursorID)
+	}
+
+	querySelect, args := q.
+		OrderBy("created_at", direction).
+		OrderBy("id", direction).
+		Limit(bReq.Limit).
+		Build()
+
+	rows, err := repository.Conn(ctx, o.db).QueryContext(ctx, querySelect, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []model.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetOrderStatusLogs is a method that retrieves the status history of an order, oldest entry first.
+func (o *store) GetOrderStatusLogs(ctx context.Context, orderID uuid.UUID) ([]model.OrderItemsLogs, error) {
+	querySelect := `
+		SELECT
+			order_id,
+			ref_code,
+			from_status,
+			to_status,
+			COALESCE(notes, ''),
+			actor,
+			created_at
+		FROM order_status_logs
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := repository.Conn(ctx, o.db).QueryContext(ctx, querySelect, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []model.OrderItemsLogs{}
+	for rows.Next() {
+		var log model.OrderItemsLogs
+		if err := rows.Scan(
+			&log.OrderID,
+			&log.RefCode,
+			&log.FromStatus,
+			&log.ToStatus,
+			&log.Notes,
+			&log.Actor,
+			&log.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// scanOrder reads a row selected with orderColumns into an order.
+func scanOrder(row rowScanner) (*model.Order, error) {
+	var order model.Order
+	var productOrder []byte
+	var refCode, transactionID sql.NullString
+	if err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.PaymentTypeID,
+		&order.OrderNumber,
+		&order.TotalPrice,
+		&productOrder,
+		&order.Status,
+		&order.IsPaid,
+		&refCode,
+		&transactionID,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.DeletedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	order.ProductOrder = productOrder
+	order.RefCode = refCode.String
+	order.TransactionID = transactionID.String
+
+	return &order, nil
 }
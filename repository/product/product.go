@@ -0,0 +1,71 @@
+package product
+
+import (
+	"cart-order-service/repository"
+	"cart-order-service/repository/internal/query"
+	model "cart-order-service/repository/models"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB) *store {
+	return &store{db}
+}
+
+// GetPrices is a method that retrieves the unit prices of the given products.
+// Products without a price are missing from the returned map.
+func (s *store) GetPrices(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	querySelect := `
+		SELECT product_id, price
+		FROM product_prices
+		WHERE product_id = ANY($1)
+	`
+
+	rows, err := repository.Conn(ctx, s.db).QueryContext(ctx, querySelect, query.UUIDs(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[uuid.UUID]float64, len(productIDs))
+	for rows.Next() {
+		var productID uuid.UUID
+		var price float64
+		if err := rows.Scan(&productID, &price); err != nil {
+			return nil, err
+		}
+		prices[productID] = price
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// SetPrice is a method that creates or replaces the unit price of a product.
+func (s *store) SetPrice(ctx context.Context, bReq model.ProductPrice) error {
+	queryUpsert := `
+		INSERT INTO product_prices (
+			product_id,
+			price,
+			created_at
+		) VALUES (
+			$1, $2, NOW()
+		)
+		ON CONFLICT (product_id) DO UPDATE SET
+			price = EXCLUDED.price,
+			updated_at = NOW()
+	`
+
+	_, err := repository.Conn(ctx, s.db).ExecContext(ctx, queryUpsert, bReq.ProductID, bReq.Price)
+	return err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"cart-order-service/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SchemaVersion is the goose version of the newest migration embedded in this binary, the version it expects.
+var SchemaVersion = migrations.Latest()
+
+// CurrentSchemaVersion returns the goose version the database is migrated to.
+// It follows goose's own rules: the newest applied version that was not rolled back afterwards.
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT version_id, is_applied
+		FROM goose_db_version
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	rolledBack := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			return 0, err
+		}
+
+		if rolledBack[version] {
+			continue
+		}
+
+		if applied {
+			return version, nil
+		}
+		rolledBack[version] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// CheckSchemaVersion returns an error unless the database is migrated to at least SchemaVersion.
+func CheckSchemaVersion(ctx context.Context, db *sql.DB) error {
+	version, err := CurrentSchemaVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d is behind expected version %d", version, SchemaVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package repository_test
+
+import (
+	"cart-order-service/repository"
+	"cart-order-service/repository/cart"
+	"cart-order-service/repository/order"
+	"cart-order-service/repository/product"
+	"cart-order-service/repository/storetest"
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// testDSNEnv is the environment variable holding the DSN of a migrated Postgres database to run the suite against.
+const testDSNEnv = "CART_ORDER_TEST_DSN"
+
+func TestPostgresStores(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := repository.CheckSchemaVersion(context.Background(), db); err != nil {
+		t.Fatalf("database is not migrated: %v", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		return storetest.Stores{
+			Cart:       cart.NewStore(db),
+			Order:      order.NewStore(db),
+			Product:    product.NewStore(db),
+			Transactor: repository.NewTransactor(db),
+		}
+	})
+}
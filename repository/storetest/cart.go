@@ -0,0 +1,305 @@
+package storetest
+
+import (
+	"cart-order-service/repository"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// errRollback makes a transaction roll back in tests.
+var errRollback = errors.New("rollback")
+
+// RunCart runs the cart store part of the suite.
+func RunCart(t *testing.T, newStores func(t *testing.T) Stores) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Stores)
+	}{
+		{"AddCartCreatesLine", testAddCartCreatesLine},
+		{"AddCartMergesDuplicateAdds", testAddCartMergesDuplicateAdds},
+		{"AddCartConcurrentDuplicateAdds", testAddCartConcurrentDuplicateAdds},
+		{"GetCartFiltersAndOrder", testGetCartFiltersAndOrder},
+		{"UpdateQty", testUpdateQty},
+		{"UpdateQtyMissingLine", testUpdateQtyMissingLine},
+		{"SoftDeleteVisibility", testSoftDeleteVisibility},
+		{"DeleteProductMissingLine", testDeleteProductMissingLine},
+		{"DeleteProducts", testDeleteProducts},
+		{"TransactionRollback", testCartTransactionRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStores(t))
+		})
+	}
+}
+
+func testAddCartCreatesLine(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID := uuid.New(), uuid.New()
+
+	id, created, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 2})
+	must(t, err)
+	if !created {
+		t.Error("first add reported a merge")
+	}
+
+	carts := getCart(t, s, userID)
+	if len(carts) != 1 {
+		t.Fatalf("got %d cart lines, want 1", len(carts))
+	}
+
+	cart := carts[0]
+	if cart.ID != *id || cart.UserID != userID || cart.ProductID != productID || cart.Qty != 2 {
+		t.Errorf("got cart line %+v, want id %s, user %s, product %s and qty 2", cart, *id, userID, productID)
+	}
+	if cart.CreatedAt == nil {
+		t.Error("created_at is not set")
+	}
+	if cart.DeletedAt != nil {
+		t.Error("new cart line is deleted")
+	}
+}
+
+func testAddCartMergesDuplicateAdds(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID := uuid.New(), uuid.New()
+
+	firstID, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 2})
+	must(t, err)
+
+	secondID, created, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 3})
+	must(t, err)
+	if created {
+		t.Error("duplicate add reported a new line")
+	}
+	if *secondID != *firstID {
+		t.Errorf("duplicate add returned line %s, want %s", *secondID, *firstID)
+	}
+
+	carts := getCart(t, s, userID)
+	if len(carts) != 1 {
+		t.Fatalf("got %d cart lines, want 1", len(carts))
+	}
+	if carts[0].Qty != 5 {
+		t.Errorf("got qty %d, want 5", carts[0].Qty)
+	}
+}
+
+func testAddCartConcurrentDuplicateAdds(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID := uuid.New(), uuid.New()
+
+	// Every add races for the same new line: exactly one creates it and the others merge into it.
+	const adds = 20
+	var wg sync.WaitGroup
+	created := make(chan bool, adds)
+	errs := make(chan error, adds)
+	for range adds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1})
+			created <- ok
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(created)
+	close(errs)
+
+	for err := range errs {
+		must(t, err)
+	}
+
+	var lines int
+	for ok := range created {
+		if ok {
+			lines++
+		}
+	}
+	if lines != 1 {
+		t.Errorf("%d adds reported a new line, want 1", lines)
+	}
+
+	carts := getCart(t, s, userID)
+	if len(carts) != 1 {
+		t.Fatalf("got %d cart lines, want 1", len(carts))
+	}
+	if carts[0].Qty != adds {
+		t.Errorf("got qty %d, want %d", carts[0].Qty, adds)
+	}
+}
+
+func testGetCartFiltersAndOrder(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, otherUserID := uuid.New(), uuid.New()
+	products := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	for _, productID := range products {
+		_, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1})
+		must(t, err)
+	}
+	_, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: otherUserID, ProductID: products[0], Qty: 1})
+	must(t, err)
+
+	carts := getCart(t, s, userID)
+	if len(carts) != len(products) {
+		t.Fatalf("got %d cart lines, want %d", len(carts), len(products))
+	}
+	for i, cart := range carts {
+		if cart.UserID != userID {
+			t.Errorf("got cart line of user %s, want %s", cart.UserID, userID)
+		}
+		if cart.ProductID != products[i] {
+			t.Errorf("cart line %d is product %s, want %s: lines must be returned oldest first", i, cart.ProductID, products[i])
+		}
+	}
+
+	filtered, err := s.Cart.GetCartByUserID(ctx, model.GetCartRequest{
+		UserID:    userID,
+		ProductID: []uuid.UUID{products[0], products[2]},
+	})
+	must(t, err)
+	if len(*filtered) != 2 || (*filtered)[0].ProductID != products[0] || (*filtered)[1].ProductID != products[2] {
+		t.Errorf("product filter returned %+v, want the lines of products %s and %s", *filtered, products[0], products[2])
+	}
+}
+
+func testUpdateQty(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID := uuid.New(), uuid.New()
+
+	_, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1})
+	must(t, err)
+	must(t, s.Cart.UpdateQty(ctx, userID, productID, 7))
+
+	carts := getCart(t, s, userID)
+	if len(carts) != 1 || carts[0].Qty != 7 {
+		t.Fatalf("got cart %+v, want a single line with qty 7", carts)
+	}
+	if carts[0].UpdatedAt == nil {
+		t.Error("updated_at is not set")
+	}
+}
+
+func testUpdateQtyMissingLine(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID := uuid.New(), uuid.New()
+
+	if err := s.Cart.UpdateQty(ctx, userID, productID, 3); !errors.Is(err, repository.ErrCartItemNotFound) {
+		t.Errorf("update of a missing line returned %v, want %v", err, repository.ErrCartItemNotFound)
+	}
+
+	_, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1})
+	must(t, err)
+	must(t, s.Cart.DeleteProduct(ctx, model.DeleteCartRequest{UserID: userID, ProductID: productID}))
+
+	if err := s.Cart.UpdateQty(ctx, userID, productID, 3); !errors.Is(err, repository.ErrCartItemNotFound) {
+		t.Errorf("update of a deleted line returned %v, want %v", err, repository.ErrCartItemNotFound)
+	}
+}
+
+func testSoftDeleteVisibility(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID, keptProductID := uuid.New(), uuid.New(), uuid.New()
+
+	deletedID, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 4})
+	must(t, err)
+	_, _, err = s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: keptProductID, Qty: 1})
+	must(t, err)
+
+	must(t, s.Cart.DeleteProduct(ctx, model.DeleteCartRequest{UserID: userID, ProductID: productID}))
+
+	carts := getCart(t, s, userID)
+	if len(carts) != 1 || carts[0].ProductID != keptProductID {
+		t.Fatalf("got cart %+v, want only the line of product %s", carts, keptProductID)
+	}
+
+	// Adding the product again starts a new line instead of reviving the deleted one.
+	id, created, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1})
+	must(t, err)
+	if !created || *id == *deletedID {
+		t.Errorf("add after delete returned line %s (created %t), want a new line", *id, created)
+	}
+
+	carts = getCart(t, s, userID)
+	if len(carts) != 2 {
+		t.Fatalf("got %d cart lines, want 2", len(carts))
+	}
+	for _, cart := range carts {
+		if cart.ProductID == productID && cart.Qty != 1 {
+			t.Errorf("got qty %d for the re-added product, want 1", cart.Qty)
+		}
+	}
+}
+
+func testDeleteProductMissingLine(t *testing.T, s Stores) {
+	ctx := testContext(t)
+
+	err := s.Cart.DeleteProduct(ctx, model.DeleteCartRequest{UserID: uuid.New(), ProductID: uuid.New()})
+	if !errors.Is(err, repository.ErrCartItemNotFound) {
+		t.Errorf("delete of a missing line returned %v, want %v", err, repository.ErrCartItemNotFound)
+	}
+}
+
+func testDeleteProducts(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID := uuid.New()
+	products := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	for _, productID := range products {
+		_, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1})
+		must(t, err)
+	}
+
+	deleted, err := s.Cart.DeleteProducts(ctx, userID, []uuid.UUID{products[0], products[1], uuid.New()})
+	must(t, err)
+	if deleted != 2 {
+		t.Errorf("deleted %d lines, want 2", deleted)
+	}
+
+	carts := getCart(t, s, userID)
+	if len(carts) != 1 || carts[0].ProductID != products[2] {
+		t.Errorf("got cart %+v, want only the line of product %s", carts, products[2])
+	}
+
+	deleted, err = s.Cart.DeleteProducts(ctx, userID, []uuid.UUID{products[0]})
+	must(t, err)
+	if deleted != 0 {
+		t.Errorf("deleting an already deleted line deleted %d lines, want 0", deleted)
+	}
+}
+
+func testCartTransactionRollback(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID, productID := uuid.New(), uuid.New()
+
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, _, err := s.Cart.AddCart(ctx, model.Cart{UserID: userID, ProductID: productID, Qty: 1}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("transaction returned %v, want %v", err, errRollback)
+	}
+
+	if carts := getCart(t, s, userID); len(carts) != 0 {
+		t.Errorf("got cart %+v after rollback, want it empty", carts)
+	}
+}
+
+// getCart returns the active cart lines of a user.
+func getCart(t *testing.T, s Stores, userID uuid.UUID) []model.Cart {
+	t.Helper()
+
+	carts, err := s.Cart.GetCartByUserID(testContext(t), model.GetCartRequest{UserID: userID})
+	must(t, err)
+	return *carts
+}
@@ -0,0 +1,418 @@
+package storetest
+
+import (
+	"cart-order-service/repository"
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// RunOrder runs the order store part of the suite.
+func RunOrder(t *testing.T, newStores func(t *testing.T) Stores) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Stores)
+	}{
+		{"CreateAndGetOrder", testCreateAndGetOrder},
+		{"MissingOrder", testMissingOrder},
+		{"StatusLogOrdering", testStatusLogOrdering},
+		{"UpdateOrder", testUpdateOrder},
+		{"UpdateOrderFromStaleStatus", testUpdateOrderFromStaleStatus},
+		{"UpdateOrderDuplicateTransaction", testUpdateOrderDuplicateTransaction},
+		{"ConcurrentStatusUpdates", testConcurrentStatusUpdates},
+		{"ListOrdersFiltersAndCursor", testListOrdersFiltersAndCursor},
+		{"TransactionRollback", testOrderTransactionRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStores(t))
+		})
+	}
+}
+
+func testCreateAndGetOrder(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	bReq := newOrder(uuid.New())
+
+	orderID, refCode, err := s.Order.CreateOrder(ctx, bReq)
+	must(t, err)
+	if *refCode != bReq.RefCode {
+		t.Errorf("got ref code %q, want %q", *refCode, bReq.RefCode)
+	}
+
+	order, err := s.Order.GetOrder(ctx, *orderID)
+	must(t, err)
+	if order.ID != *orderID ||
+		order.UserID != bReq.UserID ||
+		order.PaymentTypeID != bReq.PaymentTypeID ||
+		order.OrderNumber != bReq.OrderNumber ||
+		order.TotalPrice != bReq.TotalPrice ||
+		order.Status != model.OrderStatusPending ||
+		order.IsPaid ||
+		order.RefCode != bReq.RefCode ||
+		order.TransactionID != "" {
+		t.Errorf("got order %+v, want the created order %+v", order, bReq)
+	}
+	if order.CreatedAt == nil || order.DeletedAt != nil {
+		t.Errorf("got created_at %v and deleted_at %v, want a created, not deleted order", order.CreatedAt, order.DeletedAt)
+	}
+
+	var lines []model.ProductOrder
+	if err := json.Unmarshal(order.ProductOrder, &lines); err != nil || len(lines) != 1 || lines[0].Qty != 2 {
+		t.Errorf("got product order %s, want the created lines", order.ProductOrder)
+	}
+
+	status, err := s.Order.GetOrderStatus(ctx, *orderID)
+	must(t, err)
+	if status != model.OrderStatusPending {
+		t.Errorf("got status %q, want %q", status, model.OrderStatusPending)
+	}
+}
+
+func testMissingOrder(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	orderID := uuid.New()
+
+	if _, err := s.Order.GetOrder(ctx, orderID); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("GetOrder of a missing order returned %v, want %v", err, repository.ErrOrderNotFound)
+	}
+
+	if _, err := s.Order.GetOrderStatus(ctx, orderID); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("GetOrderStatus of a missing order returned %v, want %v", err, repository.ErrOrderNotFound)
+	}
+
+	logs, err := s.Order.GetOrderStatusLogs(ctx, orderID)
+	must(t, err)
+	if len(logs) != 0 {
+		t.Errorf("got %d status logs for a missing order, want none", len(logs))
+	}
+}
+
+func testStatusLogOrdering(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	orderID, refCode := createOrder(t, s, uuid.New())
+
+	steps := []struct{ from, to string }{
+		{"", model.OrderStatusPending},
+		{model.OrderStatusPending, model.OrderStatusPaid},
+		{model.OrderStatusPaid, model.OrderStatusProcessing},
+		{model.OrderStatusProcessing, model.OrderStatusPacking},
+	}
+	for _, step := range steps {
+		_, err := s.Order.CreateOrderItemsLogs(ctx, model.OrderItemsLogs{
+			OrderID:    orderID,
+			RefCode:    refCode,
+			FromStatus: step.from,
+			ToStatus:   step.to,
+			Notes:      "Order " + step.to,
+			Actor:      model.OrderActorSystem,
+		})
+		must(t, err)
+	}
+
+	// Logs of another order must not show up.
+	otherOrderID, otherRefCode := createOrder(t, s, uuid.New())
+	_, err := s.Order.CreateOrderItemsLogs(ctx, model.OrderItemsLogs{
+		OrderID:  otherOrderID,
+		RefCode:  otherRefCode,
+		ToStatus: model.OrderStatusPending,
+		Actor:    model.OrderActorSystem,
+	})
+	must(t, err)
+
+	logs, err := s.Order.GetOrderStatusLogs(ctx, orderID)
+	must(t, err)
+	if len(logs) != len(steps) {
+		t.Fatalf("got %d status logs, want %d", len(logs), len(steps))
+	}
+	for i, log := range logs {
+		if log.OrderID != orderID || log.RefCode != refCode || log.FromStatus != steps[i].from || log.ToStatus != steps[i].to {
+			t.Errorf("status log %d is %+v, want %s to %s: logs must be returned oldest first", i, log, steps[i].from, steps[i].to)
+		}
+		if log.Notes != "Order "+steps[i].to || log.Actor != model.OrderActorSystem || log.CreatedAt == nil {
+			t.Errorf("status log %d is %+v, want its notes, actor and creation time", i, log)
+		}
+		if i > 0 && log.CreatedAt.Before(*logs[i-1].CreatedAt) {
+			t.Errorf("status log %d was created before the previous one", i)
+		}
+	}
+}
+
+func testUpdateOrder(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	orderID, refCode := createOrder(t, s, uuid.New())
+	transactionID := uuid.NewString()
+
+	updatedRefCode, err := s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID:       orderID,
+		Status:        model.OrderStatusPaid,
+		IsPaid:        true,
+		TransactionID: transactionID,
+	}, model.OrderStatusPending)
+	must(t, err)
+	if *updatedRefCode != refCode {
+		t.Errorf("got ref code %q, want %q", *updatedRefCode, refCode)
+	}
+
+	// A later update without payment data keeps the payment recorded.
+	_, err = s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID: orderID,
+		Status:  model.OrderStatusProcessing,
+	}, model.OrderStatusPaid)
+	must(t, err)
+
+	order, err := s.Order.GetOrder(ctx, orderID)
+	must(t, err)
+	if order.Status != model.OrderStatusProcessing || !order.IsPaid || order.TransactionID != transactionID {
+		t.Errorf("got status %q, paid %t and transaction %q, want %q, true and %q",
+			order.Status, order.IsPaid, order.TransactionID, model.OrderStatusProcessing, transactionID)
+	}
+	if order.UpdatedAt == nil {
+		t.Error("updated_at is not set")
+	}
+}
+
+func testUpdateOrderFromStaleStatus(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	orderID, _ := createOrder(t, s, uuid.New())
+
+	_, err := s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID: orderID,
+		Status:  model.OrderStatusProcessing,
+	}, model.OrderStatusPaid)
+	if !errors.Is(err, repository.ErrOrderStatusChanged) {
+		t.Errorf("update from a stale status returned %v, want %v", err, repository.ErrOrderStatusChanged)
+	}
+
+	_, err = s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID: uuid.New(),
+		Status:  model.OrderStatusPaid,
+	}, model.OrderStatusPending)
+	if !errors.Is(err, repository.ErrOrderStatusChanged) {
+		t.Errorf("update of a missing order returned %v, want %v", err, repository.ErrOrderStatusChanged)
+	}
+
+	status, err := s.Order.GetOrderStatus(ctx, orderID)
+	must(t, err)
+	if status != model.OrderStatusPending {
+		t.Errorf("got status %q after a rejected update, want %q", status, model.OrderStatusPending)
+	}
+}
+
+func testUpdateOrderDuplicateTransaction(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	firstOrderID, _ := createOrder(t, s, uuid.New())
+	secondOrderID, _ := createOrder(t, s, uuid.New())
+	transactionID := uuid.NewString()
+
+	_, err := s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID:       firstOrderID,
+		Status:        model.OrderStatusPaid,
+		IsPaid:        true,
+		TransactionID: transactionID,
+	}, model.OrderStatusPending)
+	must(t, err)
+
+	_, err = s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID:       secondOrderID,
+		Status:        model.OrderStatusPaid,
+		IsPaid:        true,
+		TransactionID: transactionID,
+	}, model.OrderStatusPending)
+	if !errors.Is(err, repository.ErrDuplicateTransaction) {
+		t.Errorf("reusing a transaction ID returned %v, want %v", err, repository.ErrDuplicateTransaction)
+	}
+}
+
+func testConcurrentStatusUpdates(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	orderID, refCode := createOrder(t, s, uuid.New())
+
+	// Every updater expects the order to be pending, so exactly one of them may win.
+	const updaters = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, updaters)
+	for range updaters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				if _, err := s.Order.UpdateOrder(ctx, model.UpdateRequest{
+					OrderID: orderID,
+					Status:  model.OrderStatusPaid,
+					IsPaid:  true,
+				}, model.OrderStatusPending); err != nil {
+					return err
+				}
+
+				_, err := s.Order.CreateOrderItemsLogs(ctx, model.OrderItemsLogs{
+					OrderID:    orderID,
+					RefCode:    refCode,
+					FromStatus: model.OrderStatusPending,
+					ToStatus:   model.OrderStatusPaid,
+					Actor:      model.OrderActorPaymentGateway,
+				})
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded int
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, repository.ErrOrderStatusChanged):
+			t.Errorf("losing update returned %v, want %v", err, repository.ErrOrderStatusChanged)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent updates succeeded, want 1", succeeded)
+	}
+
+	logs, err := s.Order.GetOrderStatusLogs(ctx, orderID)
+	must(t, err)
+	if len(logs) != 1 {
+		t.Errorf("got %d status logs, want only the one of the winning update", len(logs))
+	}
+}
+
+func testListOrdersFiltersAndCursor(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID := uuid.New()
+
+	var created []uuid.UUID
+	for range 3 {
+		orderID, _ := createOrder(t, s, userID)
+		created = append(created, orderID)
+	}
+	createOrder(t, s, uuid.New())
+
+	_, err := s.Order.UpdateOrder(ctx, model.UpdateRequest{
+		OrderID: created[1],
+		Status:  model.OrderStatusCancelled,
+	}, model.OrderStatusPending)
+	must(t, err)
+
+	desc, err := s.Order.ListOrders(ctx, model.ListOrdersRequest{UserID: userID, Sort: "desc", Limit: 10})
+	must(t, err)
+	assertOrderIDs(t, "newest first", desc, created[2], created[1], created[0])
+
+	asc, err := s.Order.ListOrders(ctx, model.ListOrdersRequest{UserID: userID, Sort: "asc", Limit: 10})
+	must(t, err)
+	assertOrderIDs(t, "oldest first", asc, created[0], created[1], created[2])
+
+	pending, err := s.Order.ListOrders(ctx, model.ListOrdersRequest{UserID: userID, Status: model.OrderStatusPending, Sort: "desc", Limit: 10})
+	must(t, err)
+	assertOrderIDs(t, "pending orders", pending, created[2], created[0])
+
+	firstPage, err := s.Order.ListOrders(ctx, model.ListOrdersRequest{UserID: userID, Sort: "desc", Limit: 2})
+	must(t, err)
+	assertOrderIDs(t, "first page", firstPage, created[2], created[1])
+
+	last := firstPage[len(firstPage)-1]
+	secondPage, err := s.Order.ListOrders(ctx, model.ListOrdersRequest{
+		UserID:          userID,
+		Sort:            "desc",
+		Limit:           2,
+		CursorCreatedAt: last.CreatedAt,
+		CursorID:        last.ID,
+	})
+	must(t, err)
+	assertOrderIDs(t, "second page", secondPage, created[0])
+
+	from, to := *asc[1].CreatedAt, *asc[2].CreatedAt
+	window, err := s.Order.ListOrders(ctx, model.ListOrdersRequest{UserID: userID, Sort: "asc", Limit: 10, From: &from, To: &to})
+	must(t, err)
+	assertOrderIDs(t, "created_at window", window, created[1])
+}
+
+func testOrderTransactionRollback(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	userID := uuid.New()
+
+	var orderID uuid.UUID
+	err := s.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		id, refCode, err := s.Order.CreateOrder(ctx, newOrder(userID))
+		if err != nil {
+			return err
+		}
+		orderID = *id
+
+		if _, err := s.Order.CreateOrderItemsLogs(ctx, model.OrderItemsLogs{
+			OrderID:  *id,
+			RefCode:  *refCode,
+			ToStatus: model.OrderStatusPending,
+			Actor:    userID.String(),
+		}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("transaction returned %v, want %v", err, errRollback)
+	}
+
+	if _, err := s.Order.GetOrder(ctx, orderID); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("GetOrder after rollback returned %v, want %v", err, repository.ErrOrderNotFound)
+	}
+
+	logs, err := s.Order.GetOrderStatusLogs(ctx, orderID)
+	must(t, err)
+	if len(logs) != 0 {
+		t.Errorf("got %d status logs after rollback, want none", len(logs))
+	}
+}
+
+// newOrder returns a pending order of a user with a single product line.
+func newOrder(userID uuid.UUID) model.Order {
+	productOrder, _ := json.Marshal([]model.ProductOrder{{ProductID: uuid.New(), Qty: 2, Price: 12.5}})
+
+	return model.Order{
+		UserID:        userID,
+		PaymentTypeID: uuid.New(),
+		OrderNumber:   "ORD" + uuid.NewString(),
+		TotalPrice:    25,
+		ProductOrder:  productOrder,
+		Status:        model.OrderStatusPending,
+		RefCode:       "REF" + uuid.NewString()[:8],
+	}
+}
+
+// createOrder creates a pending order of a user and returns its ID and ref code.
+func createOrder(t *testing.T, s Stores, userID uuid.UUID) (uuid.UUID, string) {
+	t.Helper()
+
+	orderID, refCode, err := s.Order.CreateOrder(testContext(t), newOrder(userID))
+	must(t, err)
+	return *orderID, *refCode
+}
+
+// assertOrderIDs checks that orders are exactly the orders with the given IDs, in that order.
+func assertOrderIDs(t *testing.T, what string, orders []model.Order, want ...uuid.UUID) {
+	t.Helper()
+
+	got := make([]uuid.UUID, len(orders))
+	for i, order := range orders {
+		got[i] = order.ID
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("%s: got orders %v, want %v", what, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: got orders %v, want %v", what, got, want)
+			return
+		}
+	}
+}
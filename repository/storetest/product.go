@@ -0,0 +1,53 @@
+package storetest
+
+import (
+	model "cart-order-service/repository/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// RunProduct runs the product price store part of the suite.
+func RunProduct(t *testing.T, newStores func(t *testing.T) Stores) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Stores)
+	}{
+		{"SetAndGetPrices", testSetAndGetPrices},
+		{"SetPriceReplacesPrice", testSetPriceReplacesPrice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStores(t))
+		})
+	}
+}
+
+func testSetAndGetPrices(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	priced, otherPriced, unpriced := uuid.New(), uuid.New(), uuid.New()
+
+	must(t, s.Product.SetPrice(ctx, model.ProductPrice{ProductID: priced, Price: 12.5}))
+	must(t, s.Product.SetPrice(ctx, model.ProductPrice{ProductID: otherPriced, Price: 3}))
+
+	prices, err := s.Product.GetPrices(ctx, []uuid.UUID{priced, unpriced})
+	must(t, err)
+	if len(prices) != 1 || prices[priced] != 12.5 {
+		t.Errorf("got prices %v, want only %s at 12.5", prices, priced)
+	}
+}
+
+func testSetPriceReplacesPrice(t *testing.T, s Stores) {
+	ctx := testContext(t)
+	productID := uuid.New()
+
+	must(t, s.Product.SetPrice(ctx, model.ProductPrice{ProductID: productID, Price: 10}))
+	must(t, s.Product.SetPrice(ctx, model.ProductPrice{ProductID: productID, Price: 7.25}))
+
+	prices, err := s.Product.GetPrices(ctx, []uuid.UUID{productID})
+	must(t, err)
+	if prices[productID] != 7.25 {
+		t.Errorf("got price %v, want the replacing price 7.25", prices[productID])
+	}
+}
@@ -0,0 +1,81 @@
+// Package storetest is the contract test suite shared by every implementation of the cart and order stores,
+// so that the Postgres stores and the in-memory stores cannot drift apart.
+//
+// Every test works on freshly generated user and order IDs, so the suite can run against a shared database.
+package storetest
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CartStore is the cart store contract.
+type CartStore interface {
+	GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error)
+	AddCart(ctx context.Context, bReq model.Cart) (*uuid.UUID, bool, error)
+	UpdateQty(ctx context.Context, userID, productID uuid.UUID, qty int) error
+	DeleteProduct(ctx context.Context, bReq model.DeleteCartRequest) error
+	DeleteProducts(ctx context.Context, userID uuid.UUID, productIDs []uuid.UUID) (int64, error)
+}
+
+// OrderStore is the order store contract.
+type OrderStore interface {
+	CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, *string, error)
+	CreateOrderItemsLogs(ctx context.Context, bReq model.OrderItemsLogs) (*string, error)
+	GetOrderStatus(ctx context.Context, orderID uuid.UUID) (string, error)
+	UpdateOrder(ctx context.Context, bReq model.UpdateRequest, fromStatus string) (*string, error)
+	GetOrder(ctx context.Context, orderID uuid.UUID) (*model.Order, error)
+	ListOrders(ctx context.Context, bReq model.ListOrdersRequest) ([]model.Order, error)
+	GetOrderStatusLogs(ctx context.Context, orderID uuid.UUID) ([]model.OrderItemsLogs, error)
+}
+
+// ProductStore is the product price store contract.
+type ProductStore interface {
+	GetPrices(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]float64, error)
+	SetPrice(ctx context.Context, bReq model.ProductPrice) error
+}
+
+// Transactor is the unit of work shared by the stores.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Stores is one implementation of the contract.
+type Stores struct {
+	Cart       CartStore
+	Order      OrderStore
+	Product    ProductStore
+	Transactor Transactor
+}
+
+// Run runs the whole suite against the stores returned by newStores, which is called once per test.
+func Run(t *testing.T, newStores func(t *testing.T) Stores) {
+	t.Run("Cart", func(t *testing.T) {
+		RunCart(t, newStores)
+	})
+	t.Run("Order", func(t *testing.T) {
+		RunOrder(t, newStores)
+	})
+	t.Run("Product", func(t *testing.T) {
+		RunProduct(t, newStores)
+	})
+}
+
+// testContext returns a context that is cancelled when the test ends.
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// must fails the test immediately when err is not nil.
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
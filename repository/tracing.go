@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"cart-order-service/util/tracing"
+	"context"
+	"database/sql"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerScope is the instrumentation scope of the spans started by the repository layer.
+const tracerScope = "cart-order-service/repository"
+
+// tracedQuerier is a Querier that records a client span for every statement it runs.
+// Query spans end once the query returns, not once its rows have been read.
+type tracedQuerier struct {
+	q Querier
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatement(ctx, query)
+	result, err := t.q.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startStatement(ctx, query)
+	row := t.q.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// startStatement starts the span of a single SQL statement, named after its operation,
+// the first word of the statement, or QUERY for an empty one.
+// Arguments are never recorded.
+func startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	query = strings.TrimSpace(query)
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracing.Start(ctx, tracerScope, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"cart-order-service/util/tracing"
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+// txKey is the context key under which the current transaction is stored.
+type txKey struct{}
+
+// Querier is the set of query methods shared by *sql.DB and *sql.Tx.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor is a unit of work that lets several store calls commit or roll back together.
+type Transactor struct {
+	db *sql.DB
+}
+
+// NewTransactor is a constructor function that returns a new Transactor instance.
+func NewTransactor(db *sql.DB) *Transactor {
+	return &Transactor{db}
+}
+
+// WithinTransaction runs fn inside a single database transaction carried by the context passed to fn.
+// The transaction is committed when fn returns nil and rolled back otherwise.
+// If ctx already carries a transaction, fn joins it instead of starting a new one.
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithinTransaction(ctx, t.db, fn)
+}
+
+// WithinTransaction is the function form of Transactor.WithinTransaction, used by stores
+// that need several statements to run atomically.
+func WithinTransaction(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := tracing.Start(ctx, tracerScope, "transaction")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		// The rollback failure is only logged: err may be a domain error whose text reaches the client,
+		// and the driver message must not.
+		if rbErr := tx.Rollback(); rbErr != nil {
+			slog.ErrorContext(ctx, "transaction rollback failed", slog.Any("error", rbErr), slog.Any("cause", err))
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Conn returns the transaction carried by ctx, or db when ctx carries none.
+// Every statement run through it is traced.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tracedQuerier{tx}
+	}
+
+	return tracedQuerier{db}
+}
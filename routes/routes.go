@@ -3,20 +3,34 @@ package routes
 import (
 	"cart-order-service/config"
 	"cart-order-service/handlers/cart"
+	"cart-order-service/handlers/health"
 	"cart-order-service/handlers/order"
+	"cart-order-service/handlers/product"
+	"cart-order-service/util/metrics"
 	"cart-order-service/util/middleware"
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Routes struct {
-	Router *http.ServeMux
-	Cart   *cart.Handler
-	Order  *order.Handler
+	Router         *http.ServeMux
+	Logger         *slog.Logger
+	Cart           *cart.Handler
+	Order          *order.Handler
+	Product        *product.Handler
+	Health         *health.Handler
+	Metrics        *metrics.Metrics
+	Authenticate   func(http.Handler) http.Handler
+	Idempotency    func(http.Handler) http.Handler
+	VerifyCallback func(http.Handler) http.Handler
+	DBTimeout      time.Duration
 }
 
 func URLRewriter(baseURLPath string, next http.Handler) http.HandlerFunc {
@@ -34,35 +48,95 @@ func (r *Routes) SetupBaseURL() {
 	}
 }
 
+// handle registers h for pattern behind mws, followed by the CORS, logging, metrics and tracing middlewares every route shares.
+// The server span is named after pattern and continues the trace of the incoming W3C traceparent header.
+func (r *Routes) handle(pattern string, h http.HandlerFunc, mws ...func(http.Handler) http.Handler) {
+	tracing := func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, pattern)
+	}
+
+	mws = append(mws, middleware.EnabledCors, middleware.LoggerMiddleware(r.Logger), r.Metrics.Middleware(pattern), tracing)
+	r.Router.HandleFunc(pattern, middleware.ApplyMiddleware(h, mws...))
+}
+
 func (r *Routes) cartRoutes() {
-	r.Router.HandleFunc("GET /cart/{user_id}", middleware.ApplyMiddleware(r.Cart.GetCartByUserID, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("POST /cart/add", middleware.ApplyMiddleware(r.Cart.AddCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("PUT /cart/{user_id}", middleware.ApplyMiddleware(r.Cart.UpdateCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("DELETE /cart/{user_id}", middleware.ApplyMiddleware(r.Cart.DeleteCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	owner := middleware.RequireOwner("user_id")
+	customer := middleware.RequireScope(middleware.ScopeCustomer)
+
+	r.handle("GET /cart/{user_id}", r.Cart.GetCartByUserID, owner, customer, r.Authenticate)
+	r.handle("POST /cart/add", r.Cart.AddCart, customer, r.Authenticate)
+	r.handle("PUT /cart/{user_id}", r.Cart.UpdateCart, owner, customer, r.Authenticate)
+	r.handle("DELETE /cart/{user_id}", r.Cart.DeleteCart, owner, customer, r.Authenticate)
+	r.handle("POST /cart/{user_id}/checkout", r.Order.Checkout, r.Idempotency, owner, customer, r.Authenticate)
 }
 
 func (r *Routes) SetupOrder() {
-	r.Router.HandleFunc("POST /order/create", middleware.ApplyMiddleware(r.Order.CreateOrder, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("POST /order/callback", middleware.ApplyMiddleware(r.Order.UpdateOrder, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	customer := middleware.RequireScope(middleware.ScopeCustomer)
+	customerOrAdmin := middleware.RequireScope(middleware.ScopeCustomer, middleware.ScopeAdmin)
+	admin := middleware.RequireScope(middleware.ScopeAdmin)
+
+	r.handle("POST /order/create", r.Order.CreateOrder, r.Idempotency, customer, r.Authenticate)
+	r.handle("POST /order/callback", r.Order.UpdateOrder, r.Idempotency, r.VerifyCallback)
+	r.handle("GET /order", r.Order.ListOrders, customerOrAdmin, r.Authenticate)
+	r.handle("GET /order/{order_id}", r.Order.GetOrder, customerOrAdmin, r.Authenticate)
+	r.handle("GET /order/{order_id}/timeline", r.Order.GetOrderTimeline, customerOrAdmin, r.Authenticate)
+	r.handle("PUT /order/{order_id}/status", r.Order.UpdateOrderStatus, admin, r.Authenticate)
+}
+
+func (r *Routes) productRoutes() {
+	admin := middleware.RequireScope(middleware.ScopeAdmin)
+
+	r.handle("PUT /product/{product_id}/price", r.Product.SetPrice, admin, r.Authenticate)
+}
+
+// healthRoutes registers the orchestrator probes and the Prometheus endpoint at the root path, outside of BASE_URL_PATH.
+func (r *Routes) healthRoutes() {
+	r.Router.HandleFunc("GET /healthz", r.Health.Liveness)
+	r.Router.HandleFunc("GET /readyz", r.Health.Readiness)
+	r.Router.Handle("GET /metrics", r.Metrics.Handler())
 }
 
 func (r *Routes) SetupRouter() {
 	r.Router = http.NewServeMux()
+	r.healthRoutes()
 	r.SetupBaseURL()
 	r.cartRoutes()
 	r.SetupOrder()
+	r.productRoutes()
 }
 
-func (r *Routes) Run(port string) {
+// Run serves HTTP requests until ctx is cancelled, then stops accepting connections
+// and waits up to shutdownTimeout for in-flight requests to finish.
+func (r *Routes) Run(ctx context.Context, port string, shutdownTimeout time.Duration) error {
 	r.SetupRouter()
 
-	log.Printf("[Running-Success] clients on localhost on port :%s", port)
 	srv := &http.Server{
-		Handler:      r.Router,
+		Handler:      middleware.RequestID(middleware.Deadline(r.DBTimeout)(r.Router)),
 		Addr:         "localhost:" + port,
-		WriteTimeout: config.WriteTimeout() * time.Second,
-		ReadTimeout:  config.ReadTimeout() * time.Second,
+		WriteTimeout: config.WriteTimeout(),
+		ReadTimeout:  config.ReadTimeout(),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		r.Logger.Info("server started", slog.String("addr", srv.Addr))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	r.Logger.Info("shutting down server", slog.Duration("timeout", shutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server shutdown: %w", err)
 	}
 
-	log.Panic(srv.ListenAndServe())
+	r.Logger.Info("server stopped")
+	return nil
 }
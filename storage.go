@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cart-order-service/config"
+	"cart-order-service/handlers/health"
+	"cart-order-service/migrations"
+	"cart-order-service/repository"
+	"cart-order-service/repository/cart"
+	"cart-order-service/repository/idempotency"
+	"cart-order-service/repository/memory"
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/order"
+	"cart-order-service/repository/product"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store drivers selectable with STORE_DRIVER.
+const (
+	storeDriverPostgres = "postgres"
+	storeDriverMemory   = "memory"
+)
+
+// cartStore is the union of the cart store methods the usecases depend on.
+type cartStore interface {
+	GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error)
+	LockCart(ctx context.Context, userID uuid.UUID) error
+	AddCart(ctx context.Context, bReq model.Cart) (*uuid.UUID, bool, error)
+	UpdateQty(ctx context.Context, userID, productID uuid.UUID, qty int) error
+	DeleteProduct(ctx context.Context, bReq model.DeleteCartRequest) error
+	DeleteProducts(ctx context.Context, userID uuid.UUID, productIDs []uuid.UUID) (int64, error)
+}
+
+// orderStore is the set of order store methods the usecases depend on.
+type orderStore interface {
+	CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, *string, error)
+	CreateOrderItemsLogs(ctx context.Context, bReq model.OrderItemsLogs) (*string, error)
+	GetOrderStatus(ctx context.Context, orderID uuid.UUID) (string, error)
+	UpdateOrder(ctx context.Context, bReq model.UpdateRequest, fromStatus string) (*string, error)
+	GetOrder(ctx context.Context, orderID uuid.UUID) (*model.Order, error)
+	ListOrders(ctx context.Context, bReq model.ListOrdersRequest) ([]model.Order, error)
+	GetOrderStatusLogs(ctx context.Context, orderID uuid.UUID) ([]model.OrderItemsLogs, error)
+}
+
+// productStore is the union of the product price store methods used by checkout and the price admin endpoint.
+type productStore interface {
+	GetPrices(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]float64, error)
+	SetPrice(ctx context.Context, bReq model.ProductPrice) error
+}
+
+// idempotencyStore is the union of the idempotency key store methods used by the middleware and the purge worker.
+type idempotencyStore interface {
+	Reserve(ctx context.Context, bReq model.IdempotencyKey, staleAfter time.Duration) (*model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, scope, key string, reservedAt time.Time, statusCode int, body []byte) error
+	Release(ctx context.Context, scope, key string, reservedAt time.Time) error
+	DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error)
+}
+
+type transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// storage is the set of stores selected by STORE_DRIVER.
+type storage struct {
+	cart        cartStore
+	order       orderStore
+	product     productStore
+	idempotency idempotencyStore
+	transactor  transactor
+
+	// db is the database pool, nil with the memory driver.
+	db *sql.DB
+	// checks are the readiness checks of the storage backend.
+	checks []health.Check
+}
+
+// openStorage opens the stores of the configured driver: Postgres by default,
+// or in-memory stores that need no database and lose their data on restart.
+// With Postgres, pending migrations are applied first when cfg.MigrateOnStart is set,
+// and a database whose schema is behind repository.SchemaVersion is refused.
+func openStorage(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*storage, error) {
+	switch cfg.StoreDriver {
+	case "", storeDriverPostgres:
+		db, err := config.ConnectToDatabase(config.Connection{
+			Host:     cfg.DBHost,
+			Port:     cfg.DBPort,
+			User:     cfg.DBUser,
+			Password: cfg.DBPassword,
+			DBName:   cfg.DBName,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := prepareSchema(ctx, cfg, db, logger); err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		return &storage{
+			cart:        cart.NewStore(db),
+			order:       order.NewStore(db),
+			product:     product.NewStore(db),
+			idempotency: idempotency.NewStore(db),
+			transactor:  repository.NewTransactor(db),
+			db:          db,
+			checks: []health.Check{
+				{Name: "database", Run: db.PingContext},
+				{Name: "migrations", Run: func(ctx context.Context) error {
+					return repository.CheckSchemaVersion(ctx, db)
+				}},
+			},
+		}, nil
+	case storeDriverMemory:
+		db := memory.NewDB()
+
+		return &storage{
+			cart:        memory.NewCartStore(db),
+			order:       memory.NewOrderStore(db),
+			product:     memory.NewProductStore(db),
+			idempotency: memory.NewIdempotencyStore(db),
+			transactor:  db,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid store driver %q", cfg.StoreDriver)
+	}
+}
+
+// prepareSchema migrates db when cfg.MigrateOnStart is set and checks that its schema is recent enough for this binary.
+func prepareSchema(ctx context.Context, cfg *config.Config, db *sql.DB, logger *slog.Logger) error {
+	if cfg.MigrateOnStart {
+		version, err := migrations.Up(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("database migrated", slog.Int64("schema_version", version))
+	}
+
+	if err := repository.CheckSchemaVersion(ctx, db); err != nil {
+		return fmt.Errorf("%w: apply the migrations or start with --migrate", err)
+	}
+
+	return nil
+}
+
+// Close closes the database pool, if any.
+func (s *storage) Close() error {
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
@@ -2,17 +2,26 @@ package cart
 
 import (
 	model "cart-order-service/repository/models"
+	"cart-order-service/util/apperror"
+	"cart-order-service/util/tracing"
+	"context"
 
 	"github.com/google/uuid"
 )
 
+// tracerScope is the instrumentation scope of the spans started by the cart usecase.
+const tracerScope = "cart-order-service/usecase/cart"
+
+// ErrNegativeQty is returned when a cart line is asked to take a negative qty.
+var ErrNegativeQty = apperror.Validation("qty", "qty must not be negative")
+
 // cartStore is an interface that defines the methods required for managing a shopping cart.
 type cartStore interface {
 	// GetCartByUserID retrieves the cart for a given user.
-	GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error)
-	AddCart(bReq model.Cart) (*uuid.UUID, error)
-	UpdateQty(userID, productID uuid.UUID, qty int) error
-	DeleteProduct(bReq model.DeleteCartRequest) error
+	GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error)
+	AddCart(ctx context.Context, bReq model.Cart) (*uuid.UUID, bool, error)
+	UpdateQty(ctx context.Context, userID, productID uuid.UUID, qty int) error
+	DeleteProduct(ctx context.Context, bReq model.DeleteCartRequest) error
 }
 
 // cart is a struct that holds the store for managing a shopping cart.
@@ -26,8 +35,11 @@ func NewCart(store cartStore) *cart {
 }
 
 // GetCartByUserID is a method that retrieves the cart for a given user and returns a response with the total items.
-func (c *cart) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error) {
-	result, err := c.store.GetCartByUserID(bReq)
+func (c *cart) GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (_ *[]model.Cart, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "cart.GetCartByUserID")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := c.store.GetCartByUserID(ctx, bReq)
 	if err != nil {
 		return nil, err
 	}
@@ -39,18 +51,32 @@ func (c *cart) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error)
 	return result, nil
 }
 
-func (c *cart) AddCart(bReq model.Cart) (*uuid.UUID, error) {
-	id, err := c.store.AddCart(bReq)
+// AddCart is a method that adds a product to the cart, merging it into the existing line for the same product.
+func (c *cart) AddCart(ctx context.Context, bReq model.Cart) (_ *model.AddCartResponse, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "cart.AddCart")
+	defer func() { tracing.End(span, err) }()
+
+	id, created, err := c.store.AddCart(ctx, bReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return id, nil
+	return &model.AddCartResponse{
+		ID:      *id,
+		Created: created,
+	}, nil
 }
 
-func (c *cart) UpdateQty(bReq model.Cart) (string, error) {
+func (c *cart) UpdateQty(ctx context.Context, bReq model.Cart) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "cart.UpdateQty")
+	defer func() { tracing.End(span, err) }()
+
+	if bReq.Qty < 0 {
+		return "", ErrNegativeQty
+	}
+
 	if bReq.Qty == 0 {
-		if err := c.store.DeleteProduct(model.DeleteCartRequest{
+		if err := c.store.DeleteProduct(ctx, model.DeleteCartRequest{
 			UserID:    bReq.UserID,
 			ProductID: bReq.ProductID,
 		}); err != nil {
@@ -60,15 +86,18 @@ func (c *cart) UpdateQty(bReq model.Cart) (string, error) {
 		return "Product deleted from cart", nil
 	}
 
-	if err := c.store.UpdateQty(bReq.UserID, bReq.ProductID, bReq.Qty); err != nil {
+	if err := c.store.UpdateQty(ctx, bReq.UserID, bReq.ProductID, bReq.Qty); err != nil {
 		return "", err
 	}
 
 	return "Product updated in cart", nil
 }
 
-func (c *cart) DeleteCart(bReq model.DeleteCartRequest) (string, error) {
-	if err := c.store.DeleteProduct(bReq); err != nil {
+func (c *cart) DeleteCart(ctx context.Context, bReq model.DeleteCartRequest) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "cart.DeleteCart")
+	defer func() { tracing.End(span, err) }()
+
+	if err := c.store.DeleteProduct(ctx, bReq); err != nil {
 		return "", err
 	}
 
@@ -2,61 +2,409 @@ package order
 
 import (
 	model "cart-order-service/repository/models"
+	"cart-order-service/util/apperror"
+	"cart-order-service/util/tracing"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// tracerScope is the instrumentation scope of the spans started by the order usecase.
+const tracerScope = "cart-order-service/usecase/order"
+
+// ErrInvalidStatusTransition is returned when an order is asked to move to a status
+// that is not reachable from its current status.
+var ErrInvalidStatusTransition = apperror.InvalidTransition("invalid order status transition")
+
+// ErrForbidden is returned when a user asks for an order that belongs to another user.
+var ErrForbidden = apperror.Forbidden("order belongs to another user")
+
+// ErrInvalidCursor is returned when a list cursor cannot be decoded.
+var ErrInvalidCursor = apperror.Validation("cursor", "invalid cursor")
+
+// ErrEmptyCart is returned when a user checks out a cart without any active lines.
+var ErrEmptyCart = apperror.Validation("", "cart is empty")
+
+// ErrMissingPrice is returned when a product in the cart being checked out has no price.
+var ErrMissingPrice = apperror.Validation("", "no price is set")
+
+// ErrInvalidQty is returned when a line of the cart being checked out has a qty that is not positive.
+var ErrInvalidQty = apperror.Validation("qty", "cart line qty must be positive")
+
+// ErrCartChanged is returned when the cart was modified while it was being checked out.
+var ErrCartChanged = apperror.Conflict("cart changed during checkout")
+
 type orderStore interface {
-	CreateOrder(bReq model.Order) (*uuid.UUID, *string, error)
-	CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
-	UpdateOrder(bReq model.UpdateRequest) (*string, error)
+	CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, *string, error)
+	CreateOrderItemsLogs(ctx context.Context, bReq model.OrderItemsLogs) (*string, error)
+	GetOrderStatus(ctx context.Context, orderID uuid.UUID) (string, error)
+	UpdateOrder(ctx context.Context, bReq model.UpdateRequest, fromStatus string) (*string, error)
+	GetOrder(ctx context.Context, orderID uuid.UUID) (*model.Order, error)
+	ListOrders(ctx context.Context, bReq model.ListOrdersRequest) ([]model.Order, error)
+	GetOrderStatusLogs(ctx context.Context, orderID uuid.UUID) ([]model.OrderItemsLogs, error)
+}
+
+// cartStore is the part of the cart store needed to check out a cart.
+type cartStore interface {
+	GetCartByUserID(ctx context.Context, bReq model.GetCartRequest) (*[]model.Cart, error)
+	LockCart(ctx context.Context, userID uuid.UUID) error
+	DeleteProducts(ctx context.Context, userID uuid.UUID, productIDs []uuid.UUID) (int64, error)
+}
+
+// priceStore is the source of the unit prices checkout charges.
+type priceStore interface {
+	GetPrices(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]float64, error)
+}
+
+// transactor runs a function inside a single unit of work shared by every store call made with its context.
+type transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// orderMetrics records business events once their transaction has committed.
+type orderMetrics interface {
+	OrderCreated()
+	CartCheckedOut()
+	PaymentConfirmed()
+	StatusChanged(from, to string)
 }
 
 type order struct {
-	store orderStore
+	store   orderStore
+	cart    cartStore
+	prices  priceStore
+	tx      transactor
+	metrics orderMetrics
 }
 
-func NewOrder(store orderStore) *order {
-	return &order{store}
+func NewOrder(store orderStore, cart cartStore, prices priceStore, tx transactor, metrics orderMetrics) *order {
+	return &order{store, cart, prices, tx, metrics}
 }
 
-func (o *order) CreateOrder(bReq model.Order) (*uuid.UUID, error) {
-	orderID, refCode, err := o.store.CreateOrder(bReq)
+// CreateOrder is a method that creates a new order together with its first status log.
+// Both rows are written in the same transaction.
+func (o *order) CreateOrder(ctx context.Context, bReq model.Order) (_ *uuid.UUID, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "order.CreateOrder")
+	defer func() { tracing.End(span, err) }()
+
+	var orderID *uuid.UUID
+	err = o.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		id, _, err := o.createOrder(ctx, bReq)
+		if err != nil {
+			return err
+		}
+
+		orderID = id
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = o.store.CreateOrderItemsLogs(model.OrderItemsLogs{
-		OrderID:    *orderID,
-		RefCode:    *refCode,
-		FromStatus: "",
-		ToStatus:   model.OrderStatusPending,
-		Notes:      "Order created",
+	o.metrics.OrderCreated()
+	o.metrics.StatusChanged("", model.OrderStatusPending)
+
+	return orderID, nil
+}
+
+// Checkout is a method that converts the active cart of a user into a pending order.
+// Every line is charged the unit price stored for its product, never a price sent by the client.
+// The order, its status log and the removal of the checked-out cart lines commit together.
+func (o *order) Checkout(ctx context.Context, bReq model.CheckoutRequest) (_ *model.CheckoutResponse, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "order.Checkout")
+	defer func() { tracing.End(span, err) }()
+
+	var bResp *model.CheckoutResponse
+	err = o.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Hold the cart until the order is created, so that adds and qty updates
+		// cannot slip in between reading the lines and deleting them.
+		if err := o.cart.LockCart(ctx, bReq.UserID); err != nil {
+			return err
+		}
+
+		carts, err := o.cart.GetCartByUserID(ctx, model.GetCartRequest{UserID: bReq.UserID})
+		if err != nil {
+			return err
+		}
+
+		if len(*carts) == 0 {
+			return ErrEmptyCart
+		}
+
+		productIDs := make([]uuid.UUID, 0, len(*carts))
+		for _, c := range *carts {
+			productIDs = append(productIDs, c.ProductID)
+		}
+
+		prices, err := o.prices.GetPrices(ctx, productIDs)
+		if err != nil {
+			return err
+		}
+
+		var totalPrice float64
+		lines := make([]model.ProductOrder, 0, len(*carts))
+		for _, c := range *carts {
+			if c.Qty <= 0 {
+				return fmt.Errorf("%w for product %s", ErrInvalidQty, c.ProductID)
+			}
+
+			price, ok := prices[c.ProductID]
+			if !ok {
+				return fmt.Errorf("%w for product %s", ErrMissingPrice, c.ProductID)
+			}
+
+			lines = append(lines, model.ProductOrder{
+				ProductID: c.ProductID,
+				Qty:       c.Qty,
+				Price:     price,
+			})
+			totalPrice += price * float64(c.Qty)
+		}
+
+		productOrder, err := json.Marshal(lines)
+		if err != nil {
+			return err
+		}
+
+		orderID, refCode, err := o.createOrder(ctx, model.Order{
+			UserID:        bReq.UserID,
+			PaymentTypeID: bReq.PaymentTypeID,
+			OrderNumber:   bReq.OrderNumber,
+			TotalPrice:    totalPrice,
+			ProductOrder:  productOrder,
+			Status:        model.OrderStatusPending,
+			RefCode:       bReq.RefCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		deleted, err := o.cart.DeleteProducts(ctx, bReq.UserID, productIDs)
+		if err != nil {
+			return err
+		}
+
+		if deleted != int64(len(productIDs)) {
+			return ErrCartChanged
+		}
+
+		bResp = &model.CheckoutResponse{
+			OrderID: *orderID,
+			RefCode: *refCode,
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return orderID, nil
+	o.metrics.OrderCreated()
+	o.metrics.CartCheckedOut()
+	o.metrics.StatusChanged("", model.OrderStatusPending)
+
+	return bResp, nil
 }
 
-func (o *order) UpdatePayment(bReq model.UpdateRequest) (*string, error) {
-	refCode, err := o.store.UpdateOrder(bReq)
+// GetOrder is a method that retrieves an order of the given user together with its status history.
+// It returns a not found error if the order does not exist and ErrForbidden if it belongs to another user;
+// a uuid.Nil user ID skips the ownership check.
+func (o *order) GetOrder(ctx context.Context, orderID, userID uuid.UUID) (_ *model.OrderDetail, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "order.GetOrder")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := o.getOwnedOrder(ctx, orderID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = o.store.CreateOrderItemsLogs(model.OrderItemsLogs{
-		OrderID:    bReq.OrderID,
+	logs, err := o.store.GetOrderStatusLogs(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OrderDetail{
+		Order:         *order,
+		StatusHistory: logs,
+	}, nil
+}
+
+// ListOrders is a method that retrieves a page of orders and the cursor of the next page.
+// The next cursor is empty when there are no more orders.
+func (o *order) ListOrders(ctx context.Context, bReq model.ListOrdersRequest) (_ *model.ListOrdersResponse, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "order.ListOrders")
+	defer func() { tracing.End(span, err) }()
+
+	if bReq.Cursor != "" {
+		createdAt, id, err := decodeCursor(bReq.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		bReq.CursorCreatedAt, bReq.CursorID = &createdAt, id
+	}
+
+	// Fetch one extra order to know whether another page follows.
+	limit := bReq.Limit
+	bReq.Limit++
+
+	orders, err := o.store.ListOrders(ctx, bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	bResp := &model.ListOrdersResponse{Orders: orders}
+	if len(orders) > limit {
+		bResp.Orders = orders[:limit]
+		last := bResp.Orders[limit-1]
+		bResp.NextCursor = encodeCursor(*last.CreatedAt, last.ID)
+	}
+
+	return bResp, nil
+}
+
+// GetOrderTimeline is a method that retrieves the status history of an order of the given user, oldest entry first.
+// It returns the same errors as GetOrder.
+func (o *order) GetOrderTimeline(ctx context.Context, orderID, userID uuid.UUID) (_ []model.OrderItemsLogs, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "order.GetOrderTimeline")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := o.getOwnedOrder(ctx, orderID, userID); err != nil {
+		return nil, err
+	}
+
+	return o.store.GetOrderStatusLogs(ctx, orderID)
+}
+
+// getOwnedOrder retrieves an order and checks that it belongs to userID, unless userID is uuid.Nil.
+func (o *order) getOwnedOrder(ctx context.Context, orderID, userID uuid.UUID) (*model.Order, error) {
+	order, err := o.store.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != uuid.Nil && order.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	return order, nil
+}
+
+// encodeCursor builds an opaque list cursor pointing at the order with the given creation time and ID.
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+// decodeCursor is the inverse of encodeCursor.
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	createdAtStr, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	return createdAt, id, nil
+}
+
+// createOrder inserts an order and its initial status log using the transaction carried by ctx.
+func (o *order) createOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, *string, error) {
+	orderID, refCode, err := o.store.CreateOrder(ctx, bReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = o.store.CreateOrderItemsLogs(ctx, model.OrderItemsLogs{
+		OrderID:    *orderID,
 		RefCode:    *refCode,
-		FromStatus: model.OrderStatusPending,
-		ToStatus:   model.OrderStatusPaid,
-		Notes:      "Payment success",
+		FromStatus: "",
+		ToStatus:   model.OrderStatusPending,
+		Notes:      "Order created",
+		Actor:      bReq.UserID.String(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return orderID, refCode, nil
+}
+
+// UpdateStatus is a method that moves an order to the requested status.
+// The status update and its log are written in the same transaction.
+// It returns ErrInvalidStatusTransition if the order status machine does not allow the move,
+// and a conflict error if the order status changed concurrently.
+func (o *order) UpdateStatus(ctx context.Context, bReq model.UpdateRequest) (_ *string, err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "order.UpdateStatus")
+	defer func() { tracing.End(span, err) }()
+
+	var fromStatus string
+	err = o.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		fromStatus, err = o.store.GetOrderStatus(ctx, bReq.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if !model.CanTransitionOrderStatus(fromStatus, bReq.Status) {
+			return fmt.Errorf("%w: cannot move order from %s to %s", ErrInvalidStatusTransition, fromStatus, bReq.Status)
+		}
+
+		// Only a move to paid records a payment.
+		bReq.IsPaid = bReq.Status == model.OrderStatusPaid
+		if !bReq.IsPaid {
+			bReq.TransactionID = ""
+		}
+
+		refCode, err := o.store.UpdateOrder(ctx, bReq, fromStatus)
+		if err != nil {
+			return err
+		}
+
+		notes := fmt.Sprintf("Order %s", bReq.Status)
+		switch {
+		case bReq.Notes != "":
+			notes = bReq.Notes
+		case bReq.Status == model.OrderStatusPaid:
+			notes = "Payment success"
+		}
+
+		_, err = o.store.CreateOrderItemsLogs(ctx, model.OrderItemsLogs{
+			OrderID:    bReq.OrderID,
+			RefCode:    *refCode,
+			FromStatus: fromStatus,
+			ToStatus:   bReq.Status,
+			Notes:      notes,
+			Actor:      bReq.Actor,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	updateOK := "Payment Success"
+	o.metrics.StatusChanged(fromStatus, bReq.Status)
+
+	updateOK := "Order status updated"
+	if bReq.Status == model.OrderStatusPaid {
+		o.metrics.PaymentConfirmed()
+		updateOK = "Payment Success"
+	}
+
 	return &updateOK, nil
 }
@@ -0,0 +1,32 @@
+package product
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/tracing"
+	"context"
+)
+
+// tracerScope is the instrumentation scope of the spans started by the product usecase.
+const tracerScope = "cart-order-service/usecase/product"
+
+// productStore is the store of the unit prices checkout charges.
+type productStore interface {
+	SetPrice(ctx context.Context, bReq model.ProductPrice) error
+}
+
+type product struct {
+	store productStore
+}
+
+// NewProduct is a constructor function that returns a new product instance.
+func NewProduct(store productStore) *product {
+	return &product{store}
+}
+
+// SetPrice is a method that sets the unit price checkout charges for a product.
+func (p *product) SetPrice(ctx context.Context, bReq model.ProductPrice) (err error) {
+	ctx, span := tracing.Start(ctx, tracerScope, "product.SetPrice")
+	defer func() { tracing.End(span, err) }()
+
+	return p.store.SetPrice(ctx, bReq)
+}
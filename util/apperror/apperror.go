@@ -0,0 +1,109 @@
+package apperror
+
+import "errors"
+
+// Kind classifies a domain error so that the transport layer can pick a response for it.
+type Kind uint8
+
+const (
+	// KindInternal is the kind of every error that is not a domain error.
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindInvalidTransition
+	KindForbidden
+	KindValidation
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindInvalidTransition:
+		return "invalid transition"
+	case KindForbidden:
+		return "forbidden"
+	case KindValidation:
+		return "validation"
+	default:
+		return "internal"
+	}
+}
+
+// Error is a domain error returned by stores and usecases.
+// Its message is meant for clients; the wrapped error, if any, is not part of it.
+type Error struct {
+	Kind    Kind
+	Message string
+	// Field is the request field a validation error is about, if any.
+	Field string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a domain error of the same kind, field and message,
+// so that copies made by Wrap still match the error they were made from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Field == e.Field && t.Message == e.Message
+}
+
+// NotFound returns an error for a resource that does not exist.
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Conflict returns an error for a request that conflicts with the current state of a resource.
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+// InvalidTransition returns an error for a state change that is not allowed from the current state.
+func InvalidTransition(message string) *Error {
+	return &Error{Kind: KindInvalidTransition, Message: message}
+}
+
+// Forbidden returns an error for a resource the caller is not allowed to access.
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+// Validation returns an error for an invalid value of the given request field.
+func Validation(field, message string) *Error {
+	return &Error{Kind: KindValidation, Field: field, Message: message}
+}
+
+// Wrap returns a copy of e that also wraps err, keeping err out of the client message.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// As returns the first domain error in the chain of err.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+
+	return nil, false
+}
+
+// KindOf returns the kind of the first domain error in the chain of err, or KindInternal if there is none.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+
+	return KindInternal
+}
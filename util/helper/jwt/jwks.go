@@ -0,0 +1,221 @@
+package jwt
+
+import (
+	"cart-order-service/util/helper/requestid"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// minJWKSRefreshInterval limits how often the key set is refetched, both for unknown key IDs
+// and after a failed refresh of an expired key set.
+const minJWKSRefreshInterval = 30 * time.Second
+
+// jwk is a single JSON Web Key as described in RFC 7517; only public RSA and EC keys are supported.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet is a cached JWKS document loaded from a URL or a local file.
+// It is reloaded once its TTL expires, or earlier when a token refers to an unknown key ID,
+// so that keys rotated by the issuer are picked up without a restart.
+type keySet struct {
+	location string
+	ttl      time.Duration
+	client   *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]any
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func newKeySet(location string, ttl time.Duration) *keySet {
+	return &keySet{
+		location: location,
+		ttl:      ttl,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(&requestid.Transport{}),
+		},
+	}
+}
+
+// preload loads the key set for the first time so that misconfiguration is reported at startup.
+func (k *keySet) preload(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.refresh(ctx)
+}
+
+// key returns the public key with the given key ID.
+func (k *keySet) key(ctx context.Context, kid string) (any, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	_, known := k.keys[kid]
+	expired := now.Sub(k.fetchedAt) > k.ttl
+	if (expired || !known) && now.Sub(k.attemptedAt) > minJWKSRefreshInterval {
+		if err := k.refresh(ctx); err != nil && k.keys == nil {
+			return nil, err
+		}
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+// refresh reloads the key set; the previous keys are kept if loading fails.
+// Keys of unsupported types or with invalid parameters are skipped so that they cannot block the others.
+// The caller must hold k.mu.
+func (k *keySet) refresh(ctx context.Context) error {
+	k.attemptedAt = time.Now()
+
+	raw, err := k.load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(doc.Keys))
+	for _, key := range doc.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		publicKey, err := key.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+
+	if len(keys) == 0 {
+		return errors.New("JWKS has no supported signing keys")
+	}
+
+	k.keys = keys
+	k.fetchedAt = k.attemptedAt
+	return nil
+}
+
+// load reads the raw JWKS document from an http(s) URL, a file:// URL or a plain file path.
+func (k *keySet) load(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(k.location, "http://") && !strings.HasPrefix(k.location, "https://") {
+		return os.ReadFile(strings.TrimPrefix(k.location, "file://"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (j jwk) publicKey() (any, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent is too large")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -1,42 +1,88 @@
 package jwt
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var signedKey = []byte("test")
-
-func CreateRefreshToken(email string, tokenExpiry time.Duration) (string, *Payload, error) {
-	return createToken(email, tokenExpiry)
+// Config holds the key material and claim requirements used to verify tokens.
+// HMAC algorithms verify with Secret; RSA and ECDSA algorithms look the key up by key ID in the JWKS
+// document at JWKSURL, which may be an http(s) URL, a file:// URL or a local file path.
+// Issuer and Audience are only checked when set.
+type Config struct {
+	Algorithms   []string
+	Secret       string
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
 }
 
-func CreateAccessToken(email string, tokenExpiry time.Duration) (string, *Payload, error) {
-	return createToken(email, tokenExpiry)
+// Verifier checks the signature and registered claims of tokens issued by the auth service.
+type Verifier struct {
+	parser *jwt.Parser
+	secret []byte
+	jwks   *keySet
 }
 
-func createToken(email string, tokenExpiry time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(email, tokenExpiry)
-	if err != nil {
-		return "", nil, err // Added signed with error handling
+// NewVerifier is a constructor function that returns a new Verifier for the given config.
+// It returns an error when an allowed algorithm has no key material configured.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if len(cfg.Algorithms) == 0 {
+		return nil, errors.New("no JWT algorithms allowed")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	// Create token with signed
-	tokenString, err := token.SignedString(signedKey)
-	if err != nil {
-		return "", nil, err
+	v := &Verifier{secret: []byte(cfg.Secret)}
+	for _, alg := range cfg.Algorithms {
+		switch jwt.GetSigningMethod(alg).(type) {
+		case *jwt.SigningMethodHMAC:
+			if cfg.Secret == "" {
+				return nil, fmt.Errorf("algorithm %s requires a JWT secret", alg)
+			}
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+			if cfg.JWKSURL == "" {
+				return nil, fmt.Errorf("algorithm %s requires a JWKS URL", alg)
+			}
+			if v.jwks == nil {
+				v.jwks = newKeySet(cfg.JWKSURL, cfg.JWKSCacheTTL)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+		}
 	}
 
-	return tokenString, payload, nil
+	if v.jwks != nil {
+		if err := v.jwks.preload(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(cfg.Algorithms),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v, nil
 }
 
-func VerifyToken(tokenString string) (*Payload, error) {
-	// Parse token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return signedKey, nil
+// VerifyToken parses a token, checks its signature, algorithm and claims, and returns its payload.
+// ctx is used when the key set has to be fetched again.
+func (v *Verifier) VerifyToken(ctx context.Context, tokenString string) (*Payload, error) {
+	payload := &Payload{}
+	token, err := v.parser.ParseWithClaims(tokenString, payload, func(token *jwt.Token) (interface{}, error) {
+		return v.keyFunc(ctx, token)
 	})
 	if err != nil {
 		return nil, err
@@ -46,24 +92,46 @@ func VerifyToken(tokenString string) (*Payload, error) {
 		return nil, fmt.Errorf("invalid token")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, fmt.Errorf("invalid token")
+	if _, err := payload.UserID(); err != nil {
+		return nil, fmt.Errorf("subject claim is not a user ID")
+	}
+
+	return payload, nil
+}
+
+func (v *Verifier) keyFunc(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return v.secret, nil
 	}
 
-	emailInterface, ok := claims["Email"]
-	if !ok {
-		return nil, fmt.Errorf("email claim not found in token")
+	if v.jwks == nil {
+		return nil, fmt.Errorf("no key set configured for %s", token.Method.Alg())
 	}
 
-	email, ok := emailInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("email claim is not a string")
+	kid, _ := token.Header["kid"].(string)
+	return v.jwks.key(ctx, kid)
+}
+
+func CreateRefreshToken(secret string, userID uuid.UUID, email string, tokenExpiry time.Duration) (string, *Payload, error) {
+	return createToken(secret, userID, email, tokenExpiry)
+}
+
+func CreateAccessToken(secret string, userID uuid.UUID, email string, tokenExpiry time.Duration) (string, *Payload, error) {
+	return createToken(secret, userID, email, tokenExpiry)
+}
+
+func createToken(secret string, userID uuid.UUID, email string, tokenExpiry time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, email, tokenExpiry)
+	if err != nil {
+		return "", nil, err // Added signed with error handling
 	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	payload := &Payload{
-		Email: email,
+	// Create token with signed
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", nil, err
 	}
 
-	return payload, nil
+	return tokenString, payload, nil
 }
@@ -0,0 +1,291 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test_secret"
+
+// jwksServer serves a JWKS document that tests can replace, and counts how often it was fetched.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    []map[string]string
+	down    bool
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// rsaJWK returns the public JWK of key under the given key ID.
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newClaims returns valid claims of a new user, issued by user_login for the orders audience.
+func newClaims(t *testing.T) *Payload {
+	payload, err := NewPayload(uuid.New(), "user@example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload.Audience = jwt.ClaimStrings{"orders"}
+	return payload
+}
+
+func signHS256(t *testing.T, claims *Payload) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, kid string, key *rsa.PrivateKey, claims *Payload) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newVerifier(t *testing.T, cfg Config) *Verifier {
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerifyTokenClaims(t *testing.T) {
+	v := newVerifier(t, Config{
+		Algorithms: []string{"HS256"},
+		Secret:     testSecret,
+		Issuer:     "user_login",
+		Audience:   "orders",
+	})
+
+	tests := []struct {
+		name    string
+		token   func(claims *Payload) string
+		wantErr bool
+	}{
+		{
+			name:  "valid token",
+			token: func(claims *Payload) string { return signHS256(t, claims) },
+		},
+		{
+			name: "wrong issuer",
+			token: func(claims *Payload) string {
+				claims.Issuer = "someone_else"
+				return signHS256(t, claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			token: func(claims *Payload) string {
+				claims.Audience = jwt.ClaimStrings{"payments"}
+				return signHS256(t, claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func(claims *Payload) string {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return signHS256(t, claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "subject is not a user ID",
+			token: func(claims *Payload) string {
+				claims.Subject = "admin"
+				return signHS256(t, claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "signed with another secret",
+			token: func(claims *Payload) string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("other_secret"))
+				return token
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := newClaims(t)
+
+			payload, err := v.VerifyToken(context.Background(), tt.token(claims))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("token was accepted, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("token was rejected: %v", err)
+			}
+			if payload.Subject != claims.Subject {
+				t.Errorf("got subject %q, want %q", payload.Subject, claims.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenDisallowedAlgorithm(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("key-1", key))
+
+	// Only RS256 is allowed, so HS256 tokens must be rejected even when the secret is known.
+	v := newVerifier(t, Config{
+		Algorithms: []string{"RS256"},
+		Secret:     testSecret,
+		JWKSURL:    server.URL,
+	})
+
+	if _, err := v.VerifyToken(context.Background(), signHS256(t, newClaims(t))); err == nil {
+		t.Error("HS256 token was accepted by a verifier that only allows RS256")
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, newClaims(t)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.VerifyToken(context.Background(), unsigned); err == nil {
+		t.Error("unsigned token was accepted")
+	}
+
+	if _, err := v.VerifyToken(context.Background(), signRS256(t, "key-1", key, newClaims(t))); err != nil {
+		t.Errorf("RS256 token was rejected: %v", err)
+	}
+}
+
+func TestVerifyTokenUnknownKeyID(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("key-1", newRSAKey(t)))
+	v := newVerifier(t, Config{Algorithms: []string{"RS256"}, JWKSURL: server.URL, JWKSCacheTTL: time.Hour})
+
+	if _, err := v.VerifyToken(context.Background(), signRS256(t, "key-2", newRSAKey(t), newClaims(t))); err == nil {
+		t.Error("token signed with an unknown key ID was accepted")
+	}
+}
+
+func TestVerifyTokenKeyRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("key-1", oldKey))
+	v := newVerifier(t, Config{Algorithms: []string{"RS256"}, JWKSURL: server.URL, JWKSCacheTTL: time.Hour})
+
+	server.setKeys(rsaJWK("key-1", oldKey), rsaJWK("key-2", newKey))
+	rotated := signRS256(t, "key-2", newKey, newClaims(t))
+
+	// Right after the last fetch an unknown key ID does not trigger another one.
+	if _, err := v.VerifyToken(context.Background(), rotated); err == nil {
+		t.Fatal("token was accepted before the key set could be refreshed")
+	}
+
+	v.jwks.attemptedAt = time.Now().Add(-2 * minJWKSRefreshInterval)
+	if _, err := v.VerifyToken(context.Background(), rotated); err != nil {
+		t.Fatalf("token signed with the rotated key was rejected: %v", err)
+	}
+
+	if _, err := v.VerifyToken(context.Background(), signRS256(t, "key-1", oldKey, newClaims(t))); err != nil {
+		t.Errorf("token signed with the previous key was rejected: %v", err)
+	}
+}
+
+func TestVerifyTokenExpiredKeySetWhileJWKSIsDown(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("key-1", key))
+	v := newVerifier(t, Config{Algorithms: []string{"RS256"}, JWKSURL: server.URL, JWKSCacheTTL: time.Minute})
+
+	server.setDown(true)
+	v.jwks.fetchedAt = time.Now().Add(-time.Hour)
+	v.jwks.attemptedAt = v.jwks.fetchedAt
+	fetches := server.fetches.Load()
+
+	// The failed refresh keeps the cached keys, and is not retried on every request.
+	for range 5 {
+		if _, err := v.VerifyToken(context.Background(), signRS256(t, "key-1", key, newClaims(t))); err != nil {
+			t.Fatalf("token was rejected while the JWKS endpoint is down: %v", err)
+		}
+	}
+	if got := server.fetches.Load() - fetches; got != 1 {
+		t.Errorf("JWKS was fetched %d times, want 1", got)
+	}
+}
+
+func TestNewVerifierSkipsUnsupportedKeys(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t,
+		map[string]string{"kty": "OKP", "kid": "ed25519", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		map[string]string{"kty": "RSA", "kid": "broken", "n": "", "e": "AQAB"},
+		rsaJWK("key-1", key),
+	)
+	v := newVerifier(t, Config{Algorithms: []string{"RS256"}, JWKSURL: server.URL})
+
+	if _, err := v.VerifyToken(context.Background(), signRS256(t, "key-1", key, newClaims(t))); err != nil {
+		t.Errorf("token signed with the supported key was rejected: %v", err)
+	}
+}
@@ -1,19 +1,27 @@
 package jwt
 
 import (
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// DefaultScope is granted to tokens that carry neither roles nor scopes.
+const DefaultScope = "customer"
+
+// Payload holds the claims of a token; the subject claim is the ID of the authenticated user.
+// Roles and Scope (a space separated OAuth 2.0 scope string) together make up the scopes of the user.
 type Payload struct {
 	Email string
+	Roles []string `json:"roles,omitempty"`
+	Scope string   `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewPayload(email string, duration time.Duration) (*Payload, error) {
-	usrEmail, err := uuid.NewRandom()
+func NewPayload(userID uuid.UUID, email string, duration time.Duration) (*Payload, error) {
+	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
@@ -26,9 +34,25 @@ func NewPayload(email string, duration time.Duration) (*Payload, error) {
 			IssuedAt:  jwt.NewNumericDate(timeNow),
 			NotBefore: jwt.NewNumericDate(timeNow),
 			Issuer:    "user_login",
-			Subject:   "shopifun",
-			ID:        usrEmail.String(),
+			Subject:   userID.String(),
+			ID:        tokenID.String(),
 		},
 	}
 	return payload, nil
 }
+
+// UserID returns the user ID carried in the subject claim.
+func (p *Payload) UserID() (uuid.UUID, error) {
+	return uuid.Parse(p.Subject)
+}
+
+// Scopes returns the roles and scopes granted by the token, or DefaultScope if it grants none.
+func (p *Payload) Scopes() []string {
+	scopes := append([]string{}, p.Roles...)
+	scopes = append(scopes, strings.Fields(p.Scope)...)
+	if len(scopes) == 0 {
+		return []string{DefaultScope}
+	}
+
+	return scopes
+}
@@ -0,0 +1,97 @@
+package requestid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header that carries the request ID.
+const Header = "X-Request-ID"
+
+// maxLength is the longest request ID accepted from a client.
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a freshly generated request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether a client supplied request ID is safe to reuse:
+// non-empty, at most 128 characters and made of printable ASCII only.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithID stores the request ID in the context.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in the context, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Transport is an http.RoundTripper that forwards the request ID of the request context
+// to outbound HTTP calls.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	id := FromContext(req.Context())
+	if id == "" || req.Header.Get(Header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(Header, id)
+	return base.RoundTrip(req)
+}
+
+// Handler is a slog.Handler that adds the request ID of the log call context to every record.
+type Handler struct {
+	slog.Handler
+}
+
+// NewHandler wraps a slog.Handler so that records logged with a request context carry its request ID.
+func NewHandler(h slog.Handler) *Handler {
+	return &Handler{h}
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{h.Handler.WithGroup(name)}
+}
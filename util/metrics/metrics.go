@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"cart-order-service/util/middleware"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cart_order"
+
+// Metrics holds the Prometheus collectors of the service and the registry they are exposed from.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	ordersCreated     prometheus.Counter
+	paymentsConfirmed prometheus.Counter
+	cartsCheckedOut   prometheus.Counter
+	statusTransitions *prometheus.CounterVec
+}
+
+// New is a constructor function that returns a new Metrics instance with its own registry,
+// including the Go runtime, process and sql.DB connection pool collectors.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		ordersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_created_total",
+			Help:      "Number of orders created, including orders created by checkout.",
+		}),
+		paymentsConfirmed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payments_confirmed_total",
+			Help:      "Number of orders moved to paid.",
+		}),
+		cartsCheckedOut: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "carts_checked_out_total",
+			Help:      "Number of carts converted into orders.",
+		}),
+		statusTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "order_status_transitions_total",
+			Help:      "Number of order status transitions by previous and new status.",
+		}, []string{"from", "to"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.ordersCreated,
+		m.paymentsConfirmed,
+		m.cartsCheckedOut,
+		m.statusTransitions,
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+	}
+
+	return m
+}
+
+// Handler returns the handler that serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware returns a middleware that counts and times the requests served for the given route pattern.
+// The pattern, not the request path, is used as label so that path parameters do not blow up cardinality.
+func (m *Metrics) Middleware(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := middleware.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			labels := prometheus.Labels{
+				"method": r.Method,
+				"route":  route,
+				"status": strconv.Itoa(rw.StatusCode()),
+			}
+			m.httpRequests.With(labels).Inc()
+			m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// OrderCreated counts a newly created order.
+func (m *Metrics) OrderCreated() {
+	m.ordersCreated.Inc()
+}
+
+// CartCheckedOut counts a cart converted into an order.
+func (m *Metrics) CartCheckedOut() {
+	m.cartsCheckedOut.Inc()
+}
+
+// PaymentConfirmed counts an order moved to paid.
+func (m *Metrics) PaymentConfirmed() {
+	m.paymentsConfirmed.Inc()
+}
+
+// StatusChanged counts an order status transition.
+func (m *Metrics) StatusChanged(from, to string) {
+	m.statusTransitions.WithLabelValues(from, to).Inc()
+}
@@ -1,55 +1,105 @@
 package middleware
 
 import (
+	"cart-order-service/helper"
 	"cart-order-service/util/helper/jwt"
 	"context"
-	"encoding/json"
 	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
-const emailKey = "email"
+type contextKey string
+
+const (
+	userIDKey contextKey = "user_id"
+	emailKey  contextKey = "email"
+)
 
-func SetUserID(ctx context.Context, email string) context.Context {
+// SetUser stores the authenticated user in the context.
+func SetUser(ctx context.Context, userID uuid.UUID, email string) context.Context {
+	annotateUser(ctx, userID)
+	ctx = context.WithValue(ctx, userIDKey, userID)
 	ctx = context.WithValue(ctx, emailKey, email)
 	return ctx
 }
 
-func GetUserID(ctx context.Context) string {
-	userID, ok := ctx.Value(emailKey).(string)
+// GetUserID returns the ID of the authenticated user, or uuid.Nil for anonymous requests.
+func GetUserID(ctx context.Context) uuid.UUID {
+	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
 	if !ok {
-		return ""
+		return uuid.Nil
 	}
 	return userID
 }
 
-func Authentication(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"Message": "Unauthorized",
-				"Data":    nil,
-			})
-			return
-		}
-
-		tokenString = tokenString[len("Bearer "):]
-		payload, err := jwt.VerifyToken(tokenString)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"Message": "Unauthorized",
-				"Data":    nil,
-			})
-			return
-		}
-
-		ctx = SetUserID(ctx, payload.Email)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// GetEmail returns the email of the authenticated user, or an empty string for anonymous requests.
+func GetEmail(ctx context.Context) string {
+	email, ok := ctx.Value(emailKey).(string)
+	if !ok {
+		return ""
+	}
+	return email
+}
+
+// tokenVerifier is an interface that defines the method used to verify bearer tokens.
+type tokenVerifier interface {
+	VerifyToken(ctx context.Context, tokenString string) (*jwt.Payload, error)
+}
+
+// Authentication returns a middleware that rejects requests without a valid bearer token
+// and stores the user identified by the token subject, and their scopes, in the request context.
+func Authentication(verifier tokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || tokenString == "" {
+				unauthorized(w)
+				return
+			}
+
+			payload, err := verifier.VerifyToken(r.Context(), tokenString)
+			if err != nil {
+				unauthorized(w)
+				return
+			}
+
+			userID, err := payload.UserID()
+			if err != nil {
+				unauthorized(w)
+				return
+			}
+
+			ctx := SetUser(r.Context(), userID, payload.Email)
+			ctx = SetScopes(ctx, payload.Scopes())
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter) {
+	helper.HandleError(w, http.StatusUnauthorized, helper.CodeUnauthorized, "Unauthorized")
+}
+
+// RequireOwner returns a middleware that only lets the authenticated user access
+// the resources of the user ID found in the given path parameter.
+func RequireOwner(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pathUserID, err := uuid.Parse(r.PathValue(param))
+			if err != nil {
+				helper.HandleError(w, http.StatusBadRequest, helper.CodeInvalidParameter, "Invalid user ID")
+				return
+			}
+
+			if pathUserID != GetUserID(r.Context()) {
+				helper.HandleError(w, http.StatusForbidden, helper.CodeForbidden, "You are not allowed to access resources of another user")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"cart-order-service/helper"
+	"context"
+	"net/http"
+	"slices"
+)
+
+const (
+	ScopeCustomer = "customer"
+	ScopeAdmin    = "admin"
+)
+
+const scopesKey contextKey = "scopes"
+
+// SetScopes stores the scopes granted to the authenticated user in the context.
+func SetScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// GetScopes returns the scopes granted to the authenticated user.
+func GetScopes(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesKey).([]string)
+	return scopes
+}
+
+// HasScope reports whether the authenticated user was granted the given scope.
+func HasScope(ctx context.Context, scope string) bool {
+	return slices.Contains(GetScopes(ctx), scope)
+}
+
+// RequireScope returns a middleware that only lets through users granted at least one of the given scopes.
+// It must run after Authentication.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, scope := range scopes {
+				if HasScope(r.Context(), scope) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			helper.HandleError(w, http.StatusForbidden, helper.CodeForbidden, "You are not allowed to perform this operation")
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// idempotencyStore is an interface that defines the methods needed to store idempotency keys.
+type idempotencyStore interface {
+	Reserve(ctx context.Context, bReq model.IdempotencyKey, staleAfter time.Duration) (*model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, scope, key string, reservedAt time.Time, statusCode int, body []byte) error
+	Release(ctx context.Context, scope, key string, reservedAt time.Time) error
+}
+
+// Idempotency returns a middleware that makes requests carrying an Idempotency-Key header safe to retry.
+// The first request with a key runs normally and its response is stored; a retry with the same key and body
+// replays that response, a retry with a different body gets 422, and a retry while the first request is
+// still running gets 409. Keys are scoped to the method, path and authenticated user.
+// A key whose request is still unfinished after staleAfter, which must be longer than any request may run,
+// is treated as abandoned by a crashed request and taken over by the next retry; the abandoned request
+// can then no longer store or release the key.
+// Requests without the header are passed through untouched.
+func Idempotency(store idempotencyStore, staleAfter time.Duration, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				helper.HandleError(w, http.StatusBadRequest, helper.CodeBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+			if err != nil {
+				helper.HandleError(w, http.StatusRequestEntityTooLarge, helper.CodePayloadTooLarge, "Request body is too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.Sum256(body)
+			scope := r.Method + " " + r.URL.Path
+			if userID := GetUserID(r.Context()); userID != uuid.Nil {
+				scope += " " + userID.String()
+			}
+			reserved, created, err := store.Reserve(r.Context(), model.IdempotencyKey{
+				Scope:       scope,
+				Key:         key,
+				RequestHash: hex.EncodeToString(hash[:]),
+			}, staleAfter)
+			if err != nil {
+				helper.HandleInternalError(w, r, err)
+				return
+			}
+
+			if !created {
+				switch {
+				case reserved.RequestHash != hex.EncodeToString(hash[:]):
+					helper.HandleError(w, http.StatusUnprocessableEntity, helper.CodeIdempotencyMismatch, "Idempotency-Key was already used with a different request body")
+				case reserved.StatusCode == 0:
+					helper.HandleError(w, http.StatusConflict, helper.CodeIdempotencyInProgress, "A request with this Idempotency-Key is still being processed")
+				default:
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set(IdempotentReplayedHeader, "true")
+					w.WriteHeader(reserved.StatusCode)
+					w.Write(reserved.ResponseBody)
+				}
+				return
+			}
+
+			rw := NewResponseWriter(w)
+			rw.capture = &bytes.Buffer{}
+			next.ServeHTTP(rw, r)
+
+			// Server errors are not stored so that the client can retry the request with the same key.
+			ctx := context.WithoutCancel(r.Context())
+			if rw.statusCode >= http.StatusInternalServerError {
+				err = store.Release(ctx, scope, key, *reserved.CreatedAt)
+			} else {
+				err = store.Complete(ctx, scope, key, *reserved.CreatedAt, rw.statusCode, rw.capture.Bytes())
+			}
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to save idempotency key", slog.String("key", key), slog.Any("error", err))
+			}
+		})
+	}
+}
@@ -1,39 +1,63 @@
 package middleware
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
-	"net/http/httptest"
 	"time"
+
+	"github.com/google/uuid"
 )
 
-func LoggerMiddleware() func(http.Handler) http.Handler {
+const logFieldsKey contextKey = "log_fields"
+
+// logFields collects request attributes that are only known to inner middlewares,
+// such as the authenticated user, so that LoggerMiddleware can log them once the request is done.
+type logFields struct {
+	userID uuid.UUID
+}
+
+// annotateUser records the authenticated user for the request log line, if the request is being logged.
+func annotateUser(ctx context.Context, userID uuid.UUID) {
+	if fields, ok := ctx.Value(logFieldsKey).(*logFields); ok {
+		fields.userID = userID
+	}
+}
+
+// LoggerMiddleware returns a middleware that writes one structured log line per request.
+func LoggerMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			fields := &logFields{}
+			r = r.WithContext(context.WithValue(r.Context(), logFieldsKey, fields))
 
-			recorder := httptest.NewRecorder()
-			next.ServeHTTP(recorder, r)
+			rw := NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
 
-			for k, v := range recorder.Header() {
-				w.Header()[k] = v
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("proto", r.Proto),
+				slog.Int("status", rw.statusCode),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int64("bytes", rw.bytes),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			}
+			if fields.userID != uuid.Nil {
+				attrs = append(attrs, slog.String("user_id", fields.userID.String()))
 			}
-			w.WriteHeader(recorder.Code)
-			recorder.Body.WriteTo(w)
-
-			responseTime := time.Since(start).Seconds()
-			logMessage := fmt.Sprintf("%s - [%s] - \"%s %s %s\" %d %s - [%s]\n",
-				r.RemoteAddr,
-				time.Now().Format(time.RFC1123),
-				r.Method,
-				r.URL.Path,
-				r.Proto,
-				recorder.Code,
-				r.UserAgent(),
-				fmt.Sprintf("%.9fµs", responseTime),
-			)
-			log.Print(logMessage)
+
+			level := slog.LevelInfo
+			switch {
+			case rw.statusCode >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case rw.statusCode >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			logger.LogAttrs(r.Context(), level, "http request", attrs...)
 		})
 	}
 }
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"cart-order-service/util/helper/requestid"
+	"net/http"
+)
+
+// RequestID is a middleware that reuses the X-Request-ID header sent by the client, or generates one,
+// stores it in the request context and echoes it in the response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.WithID(r.Context(), id)))
+	})
+}
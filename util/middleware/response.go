@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseWriter is an http.ResponseWriter that passes every write straight through
+// while recording the status code and the number of bytes written.
+// When capture is set it also keeps a copy of the body.
+// It always implements http.Flusher and http.Hijacker and delegates to the wrapped writer,
+// so streamed and upgraded responses keep working behind the middlewares that use it.
+// Flush does nothing and Hijack returns an error when the wrapped writer lacks them.
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	bytes       int64
+	wroteHeader bool
+	capture     *bytes.Buffer
+}
+
+// NewResponseWriter is a constructor function that returns a new responseWriter wrapping w.
+// It is the status-recording writer shared by every middleware, including the metrics one.
+func NewResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+// StatusCode returns the status code written so far, 200 if none was written explicitly.
+func (rw *responseWriter) StatusCode() int {
+	return rw.statusCode
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	if !rw.wroteHeader {
+		rw.statusCode = statusCode
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += int64(n)
+	if rw.capture != nil {
+		rw.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (rw *responseWriter) Flush() {
+	rw.wroteHeader = true
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	rw.wroteHeader = true
+	if rw.statusCode == http.StatusOK {
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped writer, for use by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"cart-order-service/helper"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	maxSignedRequestBytes    = 1 << 20
+)
+
+// VerifySignature returns a middleware that only lets through requests signed with the shared secret.
+// The signature is the hex encoded HMAC-SHA256 of the X-Signature-Timestamp value, a dot and the raw body,
+// and requests whose timestamp is further than tolerance from now are rejected to prevent replays.
+// When no secret is configured every request is rejected.
+func VerifySignature(secret string, tolerance time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if secret == "" {
+				helper.HandleError(w, http.StatusUnauthorized, helper.CodeInvalidSignature, "Signature verification is not configured")
+				return
+			}
+
+			timestamp := r.Header.Get(SignatureTimestampHeader)
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				helper.HandleError(w, http.StatusUnauthorized, helper.CodeInvalidSignature, "Missing or invalid signature timestamp")
+				return
+			}
+
+			if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+				helper.HandleError(w, http.StatusUnauthorized, helper.CodeInvalidSignature, "Signature timestamp is outside the allowed window")
+				return
+			}
+
+			signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+			if err != nil || len(signature) == 0 {
+				helper.HandleError(w, http.StatusUnauthorized, helper.CodeInvalidSignature, "Missing or invalid signature")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedRequestBytes))
+			if err != nil {
+				helper.HandleError(w, http.StatusRequestEntityTooLarge, helper.CodePayloadTooLarge, "Request body is too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if !hmac.Equal(signature, Sign(secret, timestamp, body)) {
+				helper.HandleError(w, http.StatusUnauthorized, helper.CodeInvalidSignature, "Invalid signature")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Sign returns the HMAC-SHA256 signature of a request body sent at the given timestamp.
+func Sign(secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
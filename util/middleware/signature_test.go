@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const (
+		secret    = "callback_secret"
+		tolerance = 5 * time.Minute
+		body      = `{"order_id":"550e8400-e29b-41d4-a716-446655440000","status":"paid"}`
+	)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sign := func(timestamp, body string) string {
+		return hex.EncodeToString(Sign(secret, timestamp, []byte(body)))
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      string
+		want      int
+	}{
+		{
+			name:      "valid signature",
+			secret:    secret,
+			timestamp: now,
+			signature: sign(now, body),
+			body:      body,
+			want:      http.StatusOK,
+		},
+		{
+			name:      "signed with another secret",
+			secret:    secret,
+			timestamp: now,
+			signature: hex.EncodeToString(Sign("other_secret", now, []byte(body))),
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "tampered body",
+			secret:    secret,
+			timestamp: now,
+			signature: sign(now, body),
+			body:      strings.Replace(body, "paid", "cancelled", 1),
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "signature of another timestamp",
+			secret:    secret,
+			timestamp: now,
+			signature: sign(strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10), body),
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "expired timestamp",
+			secret:    secret,
+			timestamp: strconv.FormatInt(time.Now().Add(-tolerance-time.Minute).Unix(), 10),
+			signature: sign(strconv.FormatInt(time.Now().Add(-tolerance-time.Minute).Unix(), 10), body),
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "timestamp in the future",
+			secret:    secret,
+			timestamp: strconv.FormatInt(time.Now().Add(tolerance+time.Minute).Unix(), 10),
+			signature: sign(strconv.FormatInt(time.Now().Add(tolerance+time.Minute).Unix(), 10), body),
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "missing timestamp",
+			secret:    secret,
+			signature: sign("", body),
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "signature is not hex",
+			secret:    secret,
+			timestamp: now,
+			signature: "not-a-signature",
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "missing signature",
+			secret:    secret,
+			timestamp: now,
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "no secret configured",
+			timestamp: now,
+			signature: hex.EncodeToString(Sign("", now, []byte(body))),
+			body:      body,
+			want:      http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				received = string(b)
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/order/callback", strings.NewReader(tt.body))
+			if tt.timestamp != "" {
+				r.Header.Set(SignatureTimestampHeader, tt.timestamp)
+			}
+			if tt.signature != "" {
+				r.Header.Set(SignatureHeader, tt.signature)
+			}
+			w := httptest.NewRecorder()
+
+			VerifySignature(tt.secret, tolerance)(next).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK && received != tt.body {
+				t.Errorf("next handler read body %q, want %q", received, tt.body)
+			}
+			if tt.want != http.StatusOK && received != "" {
+				t.Error("next handler was called for a rejected request")
+			}
+		})
+	}
+}
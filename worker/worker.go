@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Worker is a background job that runs Run every Interval until it is stopped.
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Group runs a set of workers and stops them together.
+type Group struct {
+	logger  *slog.Logger
+	workers []Worker
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewGroup is a constructor function that returns a new Group for the given workers.
+func NewGroup(logger *slog.Logger, workers ...Worker) *Group {
+	return &Group{logger: logger, workers: workers, running: map[string]bool{}}
+}
+
+// Start launches every worker in its own goroutine.
+// Workers stop when ctx is cancelled or Stop is called.
+func (g *Group) Start(ctx context.Context) {
+	ctx, g.cancel = context.WithCancel(ctx)
+
+	for _, w := range g.workers {
+		g.wg.Add(1)
+		go func(w Worker) {
+			defer g.wg.Done()
+			g.loop(ctx, w)
+		}(w)
+	}
+}
+
+// Check returns an error naming the enabled workers that are not running.
+func (g *Group) Check(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var stopped []string
+	for _, w := range g.workers {
+		if w.Interval > 0 && !g.running[w.Name] {
+			stopped = append(stopped, w.Name)
+		}
+	}
+
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return fmt.Errorf("workers not running: %s", strings.Join(stopped, ", "))
+	}
+
+	return nil
+}
+
+func (g *Group) setRunning(name string, running bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.running[name] = running
+}
+
+// Stop cancels every worker and waits for the running jobs to return.
+func (g *Group) Stop() {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.wg.Wait()
+}
+
+func (g *Group) loop(ctx context.Context, w Worker) {
+	logger := g.logger.With(slog.String("worker", w.Name))
+	if w.Interval <= 0 {
+		logger.Warn("worker disabled, interval must be positive")
+		return
+	}
+
+	g.setRunning(w.Name, true)
+	logger.Info("worker started")
+	defer func() {
+		g.setRunning(w.Name, false)
+		logger.Info("worker stopped")
+	}()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("worker run failed", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}